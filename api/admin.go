@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"ghproxy/ban"
+	"ghproxy/config"
+	"ghproxy/proxy"
+	"ghproxy/rate"
+	"sync"
+
+	"github.com/WJQSERVER-STUDIO/go-utils/limitreader"
+	json "github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+)
+
+// adminMu 保护运行时对cfg的修改与配置文件写回
+var adminMu sync.Mutex
+
+type rateLimitUpdate struct {
+	RatePerMinute *int `json:"ratePerMinute"`
+	Burst         *int `json:"burst"`
+}
+
+type bandwidthLimitUpdate struct {
+	Enabled     *bool   `json:"enabled"`
+	TotalLimit  *string `json:"totalLimit"`
+	TotalBurst  *string `json:"totalBurst"`
+	SingleLimit *string `json:"singleLimit"`
+	SingleBurst *string `json:"singleBurst"`
+}
+
+// InitAdminRouter 初始化管理接口, 用于运行时调整限流与带宽限制
+func InitAdminRouter(cfg *config.Config, r *server.Hertz, cfgfile string, limiter *rate.RateLimiter, iplimiter *rate.IPRateLimiter) {
+	if !cfg.Admin.Enabled {
+		return
+	}
+	if cfg.Admin.Token == "" {
+		logWarning("Admin API is enabled but token is empty, admin router will not be registered")
+		return
+	}
+
+	adminRouter := r.Group("/api/admin", AdminAuthMiddleware(cfg))
+	{
+		adminRouter.GET("/rate_limit", func(ctx context.Context, c *app.RequestContext) {
+			AdminRateLimitGetHandler(cfg, c, ctx)
+		})
+		adminRouter.POST("/rate_limit", func(ctx context.Context, c *app.RequestContext) {
+			AdminRateLimitSetHandler(cfg, c, ctx, cfgfile, limiter, iplimiter)
+		})
+		adminRouter.GET("/bandwidth_limit", func(ctx context.Context, c *app.RequestContext) {
+			AdminBandwidthLimitGetHandler(cfg, c, ctx)
+		})
+		adminRouter.POST("/bandwidth_limit", func(ctx context.Context, c *app.RequestContext) {
+			AdminBandwidthLimitSetHandler(cfg, c, ctx, cfgfile)
+		})
+		adminRouter.GET("/bans", func(ctx context.Context, c *app.RequestContext) {
+			AdminBanListHandler(cfg, c, ctx)
+		})
+		adminRouter.DELETE("/bans/:ip", func(ctx context.Context, c *app.RequestContext) {
+			AdminUnbanHandler(cfg, c, ctx)
+		})
+	}
+	logInfo("Admin API router Init success")
+}
+
+// AdminAuthMiddleware 校验 GH-Admin-Token 请求头
+func AdminAuthMiddleware(cfg *config.Config) app.HandlerFunc {
+	return func(ctx context.Context, c *app.RequestContext) {
+		token := c.GetHeader("GH-Admin-Token")
+		if len(token) == 0 || subtle.ConstantTimeCompare(token, []byte(cfg.Admin.Token)) != 1 {
+			logWarning("%s %s %s %s %s Admin-Auth-Error", c.ClientIP(), c.Method(), string(c.Path()), c.Request.Header.UserAgent(), c.Request.Header.GetProtocol())
+			c.AbortWithStatusJSON(401, map[string]interface{}{
+				"error": "Unauthorized",
+			})
+			return
+		}
+		c.Next(ctx)
+	}
+}
+
+func AdminRateLimitGetHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	c.JSON(200, (map[string]interface{}{
+		"enabled":       cfg.RateLimit.Enabled,
+		"rateMethod":    cfg.RateLimit.RateMethod,
+		"ratePerMinute": cfg.RateLimit.RatePerMinute,
+		"burst":         cfg.RateLimit.Burst,
+	}))
+}
+
+func AdminRateLimitSetHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context, cfgfile string, limiter *rate.RateLimiter, iplimiter *rate.IPRateLimiter) {
+	var update rateLimitUpdate
+	if err := json.Unmarshal(c.Request.Body(), &update); err != nil {
+		c.JSON(400, map[string]interface{}{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+	if update.RatePerMinute != nil && *update.RatePerMinute <= 0 {
+		c.JSON(400, map[string]interface{}{"error": "ratePerMinute must be positive"})
+		return
+	}
+	if update.Burst != nil && *update.Burst <= 0 {
+		c.JSON(400, map[string]interface{}{"error": "burst must be positive"})
+		return
+	}
+
+	adminMu.Lock()
+	defer adminMu.Unlock()
+
+	if update.RatePerMinute != nil {
+		cfg.RateLimit.RatePerMinute = *update.RatePerMinute
+		if limiter != nil {
+			limiter.SetLimit(*update.RatePerMinute)
+		}
+		if iplimiter != nil {
+			iplimiter.SetLimit(*update.RatePerMinute)
+		}
+	}
+	if update.Burst != nil {
+		cfg.RateLimit.Burst = *update.Burst
+		if limiter != nil {
+			limiter.SetBurst(*update.Burst)
+		}
+		if iplimiter != nil {
+			iplimiter.SetBurst(*update.Burst)
+		}
+	}
+	logInfo("%s Admin updated rate limit: ratePerMinute=%d burst=%d", c.ClientIP(), cfg.RateLimit.RatePerMinute, cfg.RateLimit.Burst)
+
+	persisted, err := persistConfig(cfg, c, cfgfile)
+	if err != nil {
+		c.JSON(500, map[string]interface{}{"error": fmt.Sprintf("Failed to write config: %v", err)})
+		return
+	}
+
+	c.JSON(200, (map[string]interface{}{
+		"ratePerMinute": cfg.RateLimit.RatePerMinute,
+		"burst":         cfg.RateLimit.Burst,
+		"applied":       limiter != nil || iplimiter != nil,
+		"persisted":     persisted,
+	}))
+}
+
+func AdminBandwidthLimitGetHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	adminMu.Lock()
+	defer adminMu.Unlock()
+	c.JSON(200, (map[string]interface{}{
+		"enabled":     cfg.RateLimit.BandwidthLimit.Enabled,
+		"totalLimit":  cfg.RateLimit.BandwidthLimit.TotalLimit,
+		"totalBurst":  cfg.RateLimit.BandwidthLimit.TotalBurst,
+		"singleLimit": cfg.RateLimit.BandwidthLimit.SingleLimit,
+		"singleBurst": cfg.RateLimit.BandwidthLimit.SingleBurst,
+	}))
+}
+
+func AdminBandwidthLimitSetHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context, cfgfile string) {
+	var update bandwidthLimitUpdate
+	if err := json.Unmarshal(c.Request.Body(), &update); err != nil {
+		c.JSON(400, map[string]interface{}{"error": fmt.Sprintf("Invalid request body: %v", err)})
+		return
+	}
+
+	// 先校验所有速率字符串, 避免部分生效
+	for _, v := range []*string{update.TotalLimit, update.TotalBurst, update.SingleLimit, update.SingleBurst} {
+		if v == nil {
+			continue
+		}
+		if _, err := limitreader.ParseRate(*v); proxy.UnDefiendRateStringErrHandle(err) != nil {
+			c.JSON(400, map[string]interface{}{"error": fmt.Sprintf("Invalid rate %q: %v", *v, err)})
+			return
+		}
+	}
+
+	adminMu.Lock()
+	defer adminMu.Unlock()
+
+	bw := &cfg.RateLimit.BandwidthLimit
+	if update.Enabled != nil {
+		bw.Enabled = *update.Enabled
+	}
+	if update.TotalLimit != nil {
+		bw.TotalLimit = *update.TotalLimit
+	}
+	if update.TotalBurst != nil {
+		bw.TotalBurst = *update.TotalBurst
+	}
+	if update.SingleLimit != nil {
+		bw.SingleLimit = *update.SingleLimit
+	}
+	if update.SingleBurst != nil {
+		bw.SingleBurst = *update.SingleBurst
+	}
+
+	// 全局限速器在新建连接时生效, 已建立的连接沿用创建时的状态
+	if err := proxy.SetGlobalRateLimit(cfg); err != nil {
+		c.JSON(500, map[string]interface{}{"error": fmt.Sprintf("Failed to apply bandwidth limit: %v", err)})
+		return
+	}
+	logInfo("%s Admin updated bandwidth limit: enabled=%v total=%s/%s single=%s/%s", c.ClientIP(), bw.Enabled, bw.TotalLimit, bw.TotalBurst, bw.SingleLimit, bw.SingleBurst)
+
+	persisted, err := persistConfig(cfg, c, cfgfile)
+	if err != nil {
+		c.JSON(500, map[string]interface{}{"error": fmt.Sprintf("Failed to write config: %v", err)})
+		return
+	}
+
+	c.JSON(200, (map[string]interface{}{
+		"enabled":     bw.Enabled,
+		"totalLimit":  bw.TotalLimit,
+		"totalBurst":  bw.TotalBurst,
+		"singleLimit": bw.SingleLimit,
+		"singleBurst": bw.SingleBurst,
+		"persisted":   persisted,
+	}))
+}
+
+func AdminBanListHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	c.JSON(200, (map[string]interface{}{
+		"enabled": cfg.Ban.Enabled,
+		"bans":    ban.List(),
+	}))
+}
+
+func AdminUnbanHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	ip := c.Param("ip")
+	if !ban.Unban(ip) {
+		c.JSON(404, map[string]interface{}{"error": fmt.Sprintf("IP %s is not banned", ip)})
+		return
+	}
+	logInfo("%s Admin unbanned %s", c.ClientIP(), ip)
+	c.JSON(200, (map[string]interface{}{
+		"unbanned": ip,
+	}))
+}
+
+// persistConfig 按配置或 ?persist= 参数决定是否写回配置文件, 调用方需持有adminMu
+func persistConfig(cfg *config.Config, c *app.RequestContext, cfgfile string) (bool, error) {
+	persist := cfg.Admin.Persist
+	switch c.Query("persist") {
+	case "true", "1":
+		persist = true
+	case "false", "0":
+		persist = false
+	}
+	if !persist || cfgfile == "" {
+		return false, nil
+	}
+	if err := cfg.WriteConfig(cfgfile); err != nil {
+		logError("Failed to persist config to %s: %v", cfgfile, err)
+		return false, err
+	}
+	logInfo("Config persisted to %s", cfgfile)
+	return true, nil
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"context"
+	"ghproxy/ban"
 	"ghproxy/config"
 	"ghproxy/middleware/nocache"
+	"ghproxy/proxy"
 
 	"github.com/WJQSERVER-STUDIO/logger"
 	"github.com/cloudwego/hertz/pkg/app"
@@ -46,6 +48,30 @@ func InitHandleRouter(cfg *config.Config, r *server.Hertz, version string) {
 		apiRouter.GET("/rate_limit/limit", func(ctx context.Context, c *app.RequestContext) {
 			RateLimitLimitHandler(cfg, c, ctx)
 		})
+		apiRouter.GET("/ban/status", func(ctx context.Context, c *app.RequestContext) {
+			BanStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/cache/status", func(ctx context.Context, c *app.RequestContext) {
+			CacheStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/breaker/status", func(ctx context.Context, c *app.RequestContext) {
+			BreakerStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/dns/status", func(ctx context.Context, c *app.RequestContext) {
+			DNSStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/outbound/status", func(ctx context.Context, c *app.RequestContext) {
+			OutboundStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/checksum/status", func(ctx context.Context, c *app.RequestContext) {
+			ChecksumStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/apicache/status", func(ctx context.Context, c *app.RequestContext) {
+			APICacheStatusHandler(cfg, c, ctx)
+		})
+		apiRouter.GET("/tokenpool/status", func(ctx context.Context, c *app.RequestContext) {
+			TokenPoolStatusHandler(cfg, c, ctx)
+		})
 		apiRouter.GET("/smartgit/status", func(ctx context.Context, c *app.RequestContext) {
 			SmartGitStatusHandler(cfg, c, ctx)
 		})
@@ -110,9 +136,99 @@ func RateLimitStatusHandler(cfg *config.Config, c *app.RequestContext, ctx conte
 }
 
 func RateLimitLimitHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	// ratePerMinute 可经管理接口修改
+	adminMu.Lock()
+	ratePerMinute := cfg.RateLimit.RatePerMinute
+	adminMu.Unlock()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"RatePerMinute": ratePerMinute,
+	}))
+}
+
+func BanStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled": cfg.Ban.Enabled,
+		"banned":  len(ban.List()),
+	}))
+}
+
+func CacheStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, entries, size := proxy.DiskCacheStats()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled": enabled,
+		"entries": entries,
+		"size":    size,
+		"maxSize": cfg.Cache.MaxSize,
+	}))
+}
+
+func BreakerStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, hosts := proxy.BreakerStates()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled": enabled,
+		"hosts":   hosts,
+	}))
+}
+
+func DNSStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, servers, entries := proxy.ResolverEntries()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled": enabled,
+		"prefer":  cfg.Httpc.DNS.PreferIP,
+		"servers": servers,
+		"entries": entries,
+	}))
+}
+
+func OutboundStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, strategy, proxies := proxy.OutboundPoolStates()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled":  enabled,
+		"strategy": strategy,
+		"proxies":  proxies,
+	}))
+}
+
+func ChecksumStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	verified, mismatched, unverified, recent := proxy.ChecksumStats()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled":    cfg.Checksum.Enabled,
+		"verify":     cfg.Checksum.Verify,
+		"verified":   verified,
+		"mismatched": mismatched,
+		"unverified": unverified,
+		"mismatches": recent,
+	}))
+}
+
+func APICacheStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, stats := proxy.APICacheStats()
+	c.Response.Header.Set("Content-Type", "application/json")
+	c.JSON(200, (map[string]interface{}{
+		"enabled":     enabled,
+		"ttl":         cfg.APICache.TTL,
+		"entries":     stats.Entries,
+		"size":        stats.Size,
+		"hits":        stats.Hits,
+		"revalidated": stats.Revalidated,
+		"misses":      stats.Misses,
+	}))
+}
+
+func TokenPoolStatusHandler(cfg *config.Config, c *app.RequestContext, ctx context.Context) {
+	enabled, tokens := proxy.TokenPoolStates()
 	c.Response.Header.Set("Content-Type", "application/json")
 	c.JSON(200, (map[string]interface{}{
-		"RatePerMinute": cfg.RateLimit.RatePerMinute,
+		"enabled":  enabled,
+		"matchers": cfg.TokenPool.Matchers,
+		"tokens":   tokens,
 	}))
 }
 
@@ -0,0 +1,153 @@
+package apicache
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Entry 缓存的 API 响应
+type Entry struct {
+	Header       http.Header
+	Body         []byte
+	ETag         string
+	LastModified string
+	StoredAt     time.Time // 写入或最近一次重新验证的时间
+}
+
+// Fresh 判断条目是否仍在有效期内
+func (e *Entry) Fresh(ttl time.Duration) bool {
+	return time.Since(e.StoredAt) < ttl
+}
+
+// Revalidatable 判断条目是否带有可用于条件请求的校验值
+func (e *Entry) Revalidatable() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+func (e *Entry) size() int64 {
+	size := int64(len(e.Body))
+	for k, vs := range e.Header {
+		size += int64(len(k))
+		for _, v := range vs {
+			size += int64(len(v))
+		}
+	}
+	return size
+}
+
+type item struct {
+	key   string
+	entry *Entry
+	size  int64
+}
+
+// Cache 按总字节数淘汰的 LRU 缓存
+// 过期条目不会被主动删除, 仍可用于条件请求重新验证, 直到被容量淘汰
+type Cache struct {
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	lru      *list.List // 头部为最近使用
+	size     int64
+	maxSize  int64
+	maxEntry int64
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	revalidated atomic.Int64
+}
+
+// New 创建缓存, maxSize 为总字节数上限, maxEntry 为单个条目上限
+func New(maxSize int64, maxEntry int64) *Cache {
+	return &Cache{
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+		maxSize:  maxSize,
+		maxEntry: maxEntry,
+	}
+}
+
+// MaxEntry 返回单个条目的大小上限
+func (c *Cache) MaxEntry() int64 {
+	return c.maxEntry
+}
+
+// Get 返回条目, 条目内容不可修改
+func (c *Cache) Get(key string) (*Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*item).entry, true
+}
+
+// Put 写入或替换条目, 超出单条上限时忽略
+func (c *Cache) Put(key string, entry *Entry) {
+	size := entry.size()
+	if size > c.maxEntry || size > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+	c.items[key] = c.lru.PushFront(&item{key: key, entry: entry, size: size})
+	c.size += size
+	for c.size > c.maxSize {
+		c.removeElement(c.lru.Back())
+	}
+}
+
+// Delete 删除条目
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+	}
+}
+
+func (c *Cache) removeElement(elem *list.Element) {
+	it := elem.Value.(*item)
+	c.lru.Remove(elem)
+	delete(c.items, it.key)
+	c.size -= it.size
+}
+
+// RecordHit 记录有效期内命中
+func (c *Cache) RecordHit() { c.hits.Add(1) }
+
+// RecordMiss 记录未命中
+func (c *Cache) RecordMiss() { c.misses.Add(1) }
+
+// RecordRevalidated 记录经上游 304 重新验证后命中
+func (c *Cache) RecordRevalidated() { c.revalidated.Add(1) }
+
+// Stats 缓存统计
+type Stats struct {
+	Entries     int   `json:"entries"`
+	Size        int64 `json:"size"`
+	Hits        int64 `json:"hits"`
+	Revalidated int64 `json:"revalidated"`
+	Misses      int64 `json:"misses"`
+}
+
+// Stats 返回条目数, 占用字节数与命中统计
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	entries, size := len(c.items), c.size
+	c.mu.Unlock()
+	return Stats{
+		Entries:     entries,
+		Size:        size,
+		Hits:        c.hits.Load(),
+		Revalidated: c.revalidated.Load(),
+		Misses:      c.misses.Load(),
+	}
+}
@@ -0,0 +1,89 @@
+package apicache
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func entryOfSize(n int) *Entry {
+	return &Entry{Header: http.Header{}, Body: []byte(strings.Repeat("x", n))}
+}
+
+func TestPutEviction(t *testing.T) {
+	type put struct {
+		key  string
+		size int
+		get  string // 写入前先访问的条目, 使其成为最近使用
+	}
+	tests := []struct {
+		name     string
+		maxSize  int64
+		maxEntry int64
+		puts     []put
+		want     []string // 仍在缓存中的条目
+		gone     []string // 已被淘汰或未写入的条目
+		size     int64
+	}{
+		{
+			name: "within capacity", maxSize: 30, maxEntry: 30,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 10}, {key: "c", size: 10}},
+			want: []string{"a", "b", "c"}, size: 30,
+		},
+		{
+			name: "evict least recently put", maxSize: 30, maxEntry: 30,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 10}, {key: "c", size: 10}, {key: "d", size: 10}},
+			want: []string{"b", "c", "d"}, gone: []string{"a"}, size: 30,
+		},
+		{
+			name: "get refreshes recency", maxSize: 30, maxEntry: 30,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 10}, {key: "c", size: 10}, {key: "d", size: 10, get: "a"}},
+			want: []string{"a", "c", "d"}, gone: []string{"b"}, size: 30,
+		},
+		{
+			name: "evict several for large entry", maxSize: 30, maxEntry: 30,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 10}, {key: "c", size: 10}, {key: "d", size: 25}},
+			want: []string{"d"}, gone: []string{"a", "b", "c"}, size: 25,
+		},
+		{
+			name: "replace keeps size accurate", maxSize: 30, maxEntry: 30,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 10}, {key: "a", size: 20}},
+			want: []string{"a", "b"}, size: 30,
+		},
+		{
+			name: "entry over maxEntry ignored", maxSize: 100, maxEntry: 10,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 11}},
+			want: []string{"a"}, gone: []string{"b"}, size: 10,
+		},
+		{
+			name: "entry over maxSize ignored", maxSize: 10, maxEntry: 100,
+			puts: []put{{key: "a", size: 10}, {key: "b", size: 11}},
+			want: []string{"a"}, gone: []string{"b"}, size: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.maxSize, tt.maxEntry)
+			for _, p := range tt.puts {
+				if p.get != "" {
+					c.Get(p.get)
+				}
+				c.Put(p.key, entryOfSize(p.size))
+			}
+			for _, key := range tt.want {
+				if _, ok := c.Get(key); !ok {
+					t.Errorf("Get(%q) missing", key)
+				}
+			}
+			for _, key := range tt.gone {
+				if _, ok := c.Get(key); ok {
+					t.Errorf("Get(%q) present, want evicted", key)
+				}
+			}
+			stats := c.Stats()
+			if stats.Entries != len(tt.want) || stats.Size != tt.size {
+				t.Errorf("Stats() = %d entries, %d bytes; want %d entries, %d bytes", stats.Entries, stats.Size, len(tt.want), tt.size)
+			}
+		})
+	}
+}
@@ -24,7 +24,7 @@ func AuthHeaderHandler(c *app.RequestContext, cfg *config.Config) (isValid bool,
 		return false, fmt.Errorf("Auth token not found")
 	}
 
-	isValid = authToken == cfg.Auth.Token
+	isValid = authToken == cfg.Auth.Token || scopedToken(cfg, authToken) != nil
 	if !isValid {
 		return false, fmt.Errorf("Auth token incorrect")
 	}
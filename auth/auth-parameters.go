@@ -25,7 +25,7 @@ func AuthParametersHandler(c *app.RequestContext, cfg *config.Config) (isValid b
 		return false, fmt.Errorf("Auth token not found")
 	}
 
-	isValid = authToken == cfg.Auth.Token
+	isValid = authToken == cfg.Auth.Token || scopedToken(cfg, authToken) != nil
 	if !isValid {
 		return false, fmt.Errorf("Auth token invalid")
 	}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"ghproxy/config"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// RequestToken 按鉴权方式取出请求携带的令牌, 未启用鉴权时同样读取
+func RequestToken(c *app.RequestContext, cfg *config.Config) string {
+	switch cfg.Auth.Method {
+	case "header":
+		if cfg.Auth.Key != "" {
+			return string(c.GetHeader(cfg.Auth.Key))
+		}
+		return string(c.GetHeader("GH-Auth"))
+	case "parameters":
+		if cfg.Auth.Key != "" {
+			return c.Query(cfg.Auth.Key)
+		}
+		return c.Query("auth_token")
+	}
+	return ""
+}
+
+// scopedToken 返回与 token 匹配的 [[auth.tokens]] 条目
+func scopedToken(cfg *config.Config, token string) *config.AuthToken {
+	if token == "" {
+		return nil
+	}
+	for i := range cfg.Auth.Tokens {
+		if cfg.Auth.Tokens[i].Token == token {
+			return &cfg.Auth.Tokens[i]
+		}
+	}
+	return nil
+}
+
+// HasScope 判断请求携带的令牌是否被授予 scope
+func HasScope(c *app.RequestContext, cfg *config.Config, scope string) bool {
+	t := scopedToken(cfg, RequestToken(c, cfg))
+	if t == nil {
+		return false
+	}
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -63,28 +63,13 @@ func CheckBlacklist(username, repo string) bool {
 	if instance == nil || !instance.initialized {
 		return false
 	}
-
-	// 先检查用户级黑名单
-	if _, exists := instance.userSet[username]; exists {
-		return true
-	}
-
-	// 再检查仓库级黑名单
-	if repos, userExists := instance.repoSet[username]; userExists {
-		// 允许仓库名为空时的全用户仓库匹配
-		if repo == "" {
-			return true
-		}
-		_, repoExists := repos[repo]
-		return repoExists
-	}
-
-	return false
+	return matchUserRepo(instance.userSet, instance.repoSet, username, repo)
 }
 
 // splitUserRepo 优化分割逻辑（仅初始化时使用）
+// 以最后一个 / 分割, 嵌套命名空间(如 group/sub/project)的命名空间部分作为用户
 func splitUserRepo(fullRepo string) (user, repo string) {
-	if idx := strings.Index(fullRepo, "/"); idx > 0 {
+	if idx := strings.LastIndex(fullRepo, "/"); idx > 0 {
 		return fullRepo[:idx], fullRepo[idx+1:]
 	}
 	return fullRepo, ""
@@ -0,0 +1,36 @@
+package auth
+
+import "strings"
+
+// matchUserRepo 按命名空间逐级匹配名单
+// username 可为 GitLab 等平台的嵌套命名空间(如 group/sub), 上级命名空间的用户级条目同样生效,
+// 名单中的 group/sub 既匹配该仓库, 也匹配 group/sub 子分组下的所有仓库
+func matchUserRepo(userSet map[string]struct{}, repoSet map[string]map[string]struct{}, username, repo string) bool {
+	segs := strings.Split(username, "/")
+	for i := 1; i <= len(segs); i++ {
+		ns := strings.Join(segs[:i], "/")
+		// 用户级条目
+		if _, exists := userSet[ns]; exists {
+			return true
+		}
+		// 条目为上级命名空间下的子分组
+		if i < len(segs) {
+			if repos, exists := repoSet[ns]; exists {
+				if _, subExists := repos[segs[i]]; subExists {
+					return true
+				}
+			}
+		}
+	}
+
+	// 仓库级条目
+	if repos, userExists := repoSet[username]; userExists {
+		// 允许仓库名为空时的全用户仓库匹配
+		if repo == "" {
+			return true
+		}
+		_, repoExists := repos[repo]
+		return repoExists
+	}
+	return false
+}
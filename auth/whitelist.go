@@ -64,28 +64,13 @@ func CheckWhitelist(username, repo string) bool {
 	if whitelistInstance == nil || !whitelistInstance.initialized {
 		return false
 	}
-
-	// 先检查用户级白名单
-	if _, exists := whitelistInstance.userSet[username]; exists {
-		return true
-	}
-
-	// 再检查仓库级白名单
-	if repos, userExists := whitelistInstance.repoSet[username]; userExists {
-		// 允许仓库名为空时的全用户仓库匹配
-		if repo == "" {
-			return true
-		}
-		_, repoExists := repos[repo]
-		return repoExists
-	}
-
-	return false
+	return matchUserRepo(whitelistInstance.userSet, whitelistInstance.repoSet, username, repo)
 }
 
 // splitUserRepoWhitelist 分割用户和仓库信息（仅初始化时使用）
+// 以最后一个 / 分割, 嵌套命名空间(如 group/sub/project)的命名空间部分作为用户
 func splitUserRepoWhitelist(fullRepo string) (user, repo string) {
-	if idx := strings.Index(fullRepo, "/"); idx > 0 {
+	if idx := strings.LastIndex(fullRepo, "/"); idx > 0 {
 		return fullRepo[:idx], fullRepo[idx+1:]
 	}
 	return fullRepo, ""
@@ -0,0 +1,431 @@
+package ban
+
+import (
+	"fmt"
+	"ghproxy/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+	json "github.com/bytedance/sonic"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// Signal 触发封禁统计的信号类型
+type Signal string
+
+const (
+	SignalRateLimit  Signal = "rate_limit"  // 429 请求过于频繁
+	SignalAuth       Signal = "auth"        // 401 鉴权失败
+	SignalList       Signal = "list"        // 403 黑白名单拦截
+	SignalInvalidURL Signal = "invalid_url" // 400 无效URL
+)
+
+// Entry 封禁记录
+type Entry struct {
+	IP        string    `json:"ip"`
+	Reason    Signal    `json:"reason"`
+	Offenses  int       `json:"offenses"` // 累计被封禁次数, 用于计算递增的封禁时长
+	BannedAt  time.Time `json:"bannedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// counter 固定窗口计数器
+type counter struct {
+	count       int
+	windowStart time.Time
+}
+
+// Banner 基于信号计数的临时封禁器
+type Banner struct {
+	mu         sync.Mutex
+	counters   map[string]map[Signal]*counter // ip -> signal -> 计数
+	bans       map[string]*Entry              // 当前生效的封禁
+	offenses   map[string]int                 // ip -> 历史封禁次数
+	lastExpiry map[string]time.Time           // ip -> 最近一次封禁的到期时间, 用于清零封禁次数
+	thresholds map[Signal]int
+
+	window         time.Duration
+	banDuration    time.Duration
+	maxBanDuration time.Duration
+	offenseDecay   time.Duration // 为0表示不清零
+	persistFile    string
+	saveMu         sync.Mutex // 串行化持久化文件的写入
+
+	stopCleanup chan struct{}
+}
+
+var instance *Banner
+
+const cleanupInterval = 1 * time.Minute
+
+// Init 根据配置初始化全局封禁器, 并从持久化文件恢复封禁列表
+func Init(cfg *config.Config) {
+	if !cfg.Ban.Enabled {
+		return
+	}
+	instance = New(cfg.Ban)
+	if err := instance.load(); err != nil {
+		logWarning("Failed to load ban list: %v", err)
+	}
+	logInfo("Ban module initialized with window: %v, banDuration: %v, maxBanDuration: %v, offenseDecay: %v", instance.window, instance.banDuration, instance.maxBanDuration, instance.offenseDecay)
+}
+
+// New 创建一个封禁器
+func New(cfg config.BanConfig) *Banner {
+	window := time.Duration(cfg.Window) * time.Second
+	if window <= 0 {
+		window = 1 * time.Minute
+		logWarning("ban window must be positive, setting to 60s")
+	}
+	banDuration := time.Duration(cfg.BanDuration) * time.Second
+	if banDuration <= 0 {
+		banDuration = 5 * time.Minute
+		logWarning("ban duration must be positive, setting to 300s")
+	}
+	maxBanDuration := time.Duration(cfg.MaxBanDuration) * time.Second
+	if maxBanDuration < banDuration {
+		maxBanDuration = banDuration
+	}
+	offenseDecay := time.Duration(cfg.OffenseDecay) * time.Second
+	if offenseDecay < 0 {
+		offenseDecay = 0
+	}
+
+	b := &Banner{
+		counters:   make(map[string]map[Signal]*counter),
+		bans:       make(map[string]*Entry),
+		offenses:   make(map[string]int),
+		lastExpiry: make(map[string]time.Time),
+		thresholds: map[Signal]int{
+			SignalRateLimit:  cfg.RateLimitThreshold,
+			SignalAuth:       cfg.AuthThreshold,
+			SignalList:       cfg.ListThreshold,
+			SignalInvalidURL: cfg.InvalidURLThreshold,
+		},
+		window:         window,
+		banDuration:    banDuration,
+		maxBanDuration: maxBanDuration,
+		offenseDecay:   offenseDecay,
+		persistFile:    cfg.PersistFile,
+		stopCleanup:    make(chan struct{}),
+	}
+	go b.cleanupLoop()
+	return b
+}
+
+// Record 记录一次信号, 超过阈值时封禁该IP; 未启用时为空操作
+func Record(ip string, signal Signal) {
+	if instance == nil {
+		return
+	}
+	instance.Record(ip, signal)
+}
+
+// IsBanned 检查IP是否处于封禁中; 未启用时始终返回false
+func IsBanned(ip string) (*Entry, bool) {
+	if instance == nil {
+		return nil, false
+	}
+	return instance.IsBanned(ip)
+}
+
+// List 返回当前生效的封禁列表
+func List() []Entry {
+	if instance == nil {
+		return []Entry{}
+	}
+	return instance.List()
+}
+
+// Unban 手动解除IP封禁
+func Unban(ip string) bool {
+	if instance == nil {
+		return false
+	}
+	return instance.Unban(ip)
+}
+
+// Enabled 返回封禁模块是否已启用
+func Enabled() bool {
+	return instance != nil
+}
+
+// Record 记录一次信号, 超过阈值时封禁该IP
+func (b *Banner) Record(ip string, signal Signal) {
+	if ip == "" {
+		return
+	}
+	threshold := b.thresholds[signal]
+	if threshold <= 0 {
+		return // 阈值为0表示不统计该信号
+	}
+
+	now := time.Now()
+
+	b.mu.Lock()
+	if _, banned := b.bans[ip]; banned {
+		b.mu.Unlock()
+		return
+	}
+
+	signals, ok := b.counters[ip]
+	if !ok {
+		signals = make(map[Signal]*counter)
+		b.counters[ip] = signals
+	}
+	cnt, ok := signals[signal]
+	if !ok || now.Sub(cnt.windowStart) > b.window {
+		cnt = &counter{windowStart: now}
+		signals[signal] = cnt
+	}
+	cnt.count++
+
+	if cnt.count < threshold {
+		b.mu.Unlock()
+		return
+	}
+
+	// 达到阈值, 按历史封禁次数递增封禁时长
+	b.offenses[ip]++
+	offenses := b.offenses[ip]
+	duration := b.banDuration
+	for i := 1; i < offenses && duration < b.maxBanDuration; i++ {
+		duration *= 2
+	}
+	if duration > b.maxBanDuration {
+		duration = b.maxBanDuration
+	}
+	entry := &Entry{
+		IP:        ip,
+		Reason:    signal,
+		Offenses:  offenses,
+		BannedAt:  now,
+		ExpiresAt: now.Add(duration),
+	}
+	b.bans[ip] = entry
+	b.lastExpiry[ip] = entry.ExpiresAt
+	delete(b.counters, ip)
+	b.mu.Unlock()
+
+	logWarning("%s Banned for %v, reason: %s, offenses: %d", ip, duration, signal, offenses)
+	b.save()
+}
+
+// IsBanned 检查IP是否处于封禁中
+func (b *Banner) IsBanned(ip string) (*Entry, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	entry, ok := b.bans[ip]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		delete(b.bans, ip)
+		return nil, false
+	}
+	e := *entry
+	return &e, true
+}
+
+// List 返回当前生效的封禁列表, 按到期时间排序
+func (b *Banner) List() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	list := make([]Entry, 0, len(b.bans))
+	for _, entry := range b.bans {
+		if now.After(entry.ExpiresAt) {
+			continue
+		}
+		list = append(list, *entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ExpiresAt.Before(list[j].ExpiresAt)
+	})
+	return list
+}
+
+// Unban 手动解除IP封禁, 同时清除其历史封禁次数
+func (b *Banner) Unban(ip string) bool {
+	b.mu.Lock()
+	_, ok := b.bans[ip]
+	delete(b.bans, ip)
+	delete(b.offenses, ip)
+	delete(b.lastExpiry, ip)
+	delete(b.counters, ip)
+	b.mu.Unlock()
+
+	if ok {
+		logInfo("%s Unbanned", ip)
+		b.save()
+	}
+	return ok
+}
+
+// Stop 停止后台清理并写回封禁列表
+func (b *Banner) Stop() {
+	close(b.stopCleanup)
+	b.save()
+}
+
+func (b *Banner) cleanupLoop() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.cleanup()
+		case <-b.stopCleanup:
+			return
+		}
+	}
+}
+
+// cleanup 清理过期封禁与过期计数器, 并清零已超过 offenseDecay 未再被封禁的IP的封禁次数
+func (b *Banner) cleanup() {
+	now := time.Now()
+	removed, decayed := 0, 0
+
+	b.mu.Lock()
+	for ip, entry := range b.bans {
+		if now.After(entry.ExpiresAt) {
+			delete(b.bans, ip)
+			removed++
+		}
+	}
+	for ip, signals := range b.counters {
+		for signal, cnt := range signals {
+			if now.Sub(cnt.windowStart) > b.window {
+				delete(signals, signal)
+			}
+		}
+		if len(signals) == 0 {
+			delete(b.counters, ip)
+		}
+	}
+	if b.offenseDecay > 0 {
+		for ip := range b.offenses {
+			if _, banned := b.bans[ip]; banned {
+				continue
+			}
+			if last, ok := b.lastExpiry[ip]; !ok || now.Sub(last) > b.offenseDecay {
+				delete(b.offenses, ip)
+				delete(b.lastExpiry, ip)
+				decayed++
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	if removed > 0 || decayed > 0 {
+		logDebug("Ban cleanup removed %d expired entries, reset %d offense counts", removed, decayed)
+		b.save()
+	}
+}
+
+// persistData 持久化文件格式
+type persistData struct {
+	Bans       []Entry              `json:"bans"`
+	Offenses   map[string]int       `json:"offenses"`
+	LastExpiry map[string]time.Time `json:"lastExpiry"`
+}
+
+// save 将封禁列表写入持久化文件
+// 并发调用按顺序执行, 快照在持有 saveMu 时获取, 保证最后写入的是最新状态
+func (b *Banner) save() {
+	if b.persistFile == "" {
+		return
+	}
+
+	b.saveMu.Lock()
+	defer b.saveMu.Unlock()
+
+	b.mu.Lock()
+	data := persistData{
+		Bans:       make([]Entry, 0, len(b.bans)),
+		Offenses:   make(map[string]int, len(b.offenses)),
+		LastExpiry: make(map[string]time.Time, len(b.lastExpiry)),
+	}
+	for _, entry := range b.bans {
+		data.Bans = append(data.Bans, *entry)
+	}
+	for ip, n := range b.offenses {
+		data.Offenses[ip] = n
+	}
+	for ip, t := range b.lastExpiry {
+		data.LastExpiry[ip] = t
+	}
+	b.mu.Unlock()
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logError("Failed to encode ban list: %v", err)
+		return
+	}
+
+	// 先写临时文件再重命名, 避免写入中断导致文件损坏
+	tmp := b.persistFile + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(b.persistFile), 0755); err != nil {
+		logError("Failed to create ban list dir: %v", err)
+		return
+	}
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		logError("Failed to write ban list: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, b.persistFile); err != nil {
+		logError("Failed to rename ban list: %v", err)
+	}
+}
+
+// load 从持久化文件恢复封禁列表, 已过期的记录会被丢弃
+func (b *Banner) load() error {
+	if b.persistFile == "" || !config.FileExists(b.persistFile) {
+		return nil
+	}
+	raw, err := os.ReadFile(b.persistFile)
+	if err != nil {
+		return fmt.Errorf("failed to read ban list: %w", err)
+	}
+	var data persistData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("invalid ban list format: %w", err)
+	}
+
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range data.Bans {
+		entry := data.Bans[i]
+		if now.After(entry.ExpiresAt) {
+			continue
+		}
+		b.bans[entry.IP] = &entry
+	}
+	for ip, n := range data.Offenses {
+		b.offenses[ip] = n
+		// 旧格式的文件没有到期时间, 从加载时开始计算清零时长
+		if t, ok := data.LastExpiry[ip]; ok {
+			b.lastExpiry[ip] = t
+		} else {
+			b.lastExpiry[ip] = now
+		}
+	}
+	logInfo("Loaded %d active bans from %s", len(b.bans), b.persistFile)
+	return nil
+}
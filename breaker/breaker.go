@@ -0,0 +1,228 @@
+package breaker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// ErrOpen 熔断器处于打开状态, 请求被快速拒绝
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State 熔断器状态
+type State string
+
+const (
+	StateClosed   State = "closed"    // 正常放行
+	StateOpen     State = "open"      // 快速失败
+	StateHalfOpen State = "half-open" // 放行少量探测请求
+)
+
+// Result 一次请求的结果
+type Result int
+
+const (
+	ResultSuccess Result = iota
+	ResultFailure
+	ResultIgnore // 客户端取消等与上游健康无关的结果
+)
+
+// host 单个上游主机的熔断状态
+type host struct {
+	state     State
+	failures  int    // 连续失败次数
+	probes    int    // 半开状态下进行中的探测请求数
+	halfOpens uint64 // 进入半开状态的次数, 用于识别过期的探测令牌
+	openedAt  time.Time
+	lastError string
+
+	totalSuccess int64
+	totalFailure int64
+	totalReject  int64
+}
+
+// HostState 对外暴露的主机熔断状态
+type HostState struct {
+	Host         string    `json:"host"`
+	State        State     `json:"state"`
+	Failures     int       `json:"failures"`
+	OpenedAt     time.Time `json:"openedAt"`
+	RetryAt      time.Time `json:"retryAt"`
+	LastError    string    `json:"lastError,omitempty"`
+	TotalSuccess int64     `json:"totalSuccess"`
+	TotalFailure int64     `json:"totalFailure"`
+	TotalReject  int64     `json:"totalReject"`
+}
+
+// Token 由 Allow 发放, 调用 Report 时原样传回
+// 仅半开状态下放行的探测请求持有探测令牌, 只有它们会释放探测名额并决定半开状态的去向
+type Token struct {
+	probe     bool
+	halfOpens uint64
+}
+
+// Probe 返回该请求是否为半开状态下的探测请求
+func (t Token) Probe() bool {
+	return t.probe
+}
+
+// Breaker 按上游主机划分的熔断器
+type Breaker struct {
+	mu    sync.Mutex
+	hosts map[string]*host
+
+	threshold   int
+	openTimeout time.Duration
+	maxProbes   int
+}
+
+// New 创建熔断器
+// threshold: 连续失败多少次后打开
+// openTimeout: 打开后多久进入半开状态
+// maxProbes: 半开状态下允许的并发探测请求数
+func New(threshold int, openTimeout time.Duration, maxProbes int) *Breaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if openTimeout <= 0 {
+		openTimeout = 30 * time.Second
+	}
+	if maxProbes <= 0 {
+		maxProbes = 1
+	}
+	return &Breaker{
+		hosts:       make(map[string]*host),
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		maxProbes:   maxProbes,
+	}
+}
+
+// Allow 判断是否放行发往 hostname 的请求, 放行后必须携带返回的令牌调用 Report 报告结果
+func (b *Breaker) Allow(hostname string) (Token, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h, ok := b.hosts[hostname]
+	if !ok {
+		h = &host{state: StateClosed}
+		b.hosts[hostname] = h
+	}
+
+	switch h.state {
+	case StateOpen:
+		if time.Since(h.openedAt) < b.openTimeout {
+			h.totalReject++
+			return Token{}, ErrOpen
+		}
+		h.state = StateHalfOpen
+		h.probes = 0
+		h.halfOpens++
+		logInfo("Circuit breaker for %s is half-open, probing upstream", hostname)
+		fallthrough
+	case StateHalfOpen:
+		if h.probes >= b.maxProbes {
+			h.totalReject++
+			return Token{}, ErrOpen
+		}
+		h.probes++
+		return Token{probe: true, halfOpens: h.halfOpens}, nil
+	}
+	return Token{}, nil
+}
+
+// Report 报告一次已放行请求的结果
+// 打开或半开状态下只有本轮的探测请求会释放名额并改变状态, 其余迟到的结果只计入统计
+func (b *Breaker) Report(hostname string, token Token, result Result, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h, ok := b.hosts[hostname]
+	if !ok {
+		return
+	}
+	current := h.state == StateHalfOpen && token.probe && token.halfOpens == h.halfOpens
+	if current && h.probes > 0 {
+		h.probes--
+	}
+
+	switch result {
+	case ResultSuccess:
+		h.totalSuccess++
+		if h.state != StateClosed && !current {
+			return
+		}
+		h.failures = 0
+		if h.state != StateClosed {
+			h.state = StateClosed
+			logInfo("Circuit breaker for %s is closed, upstream recovered", hostname)
+		}
+	case ResultFailure:
+		h.totalFailure++
+		h.failures++
+		if err != nil {
+			h.lastError = err.Error()
+		}
+		if current || (h.state == StateClosed && h.failures >= b.threshold) {
+			h.state = StateOpen
+			h.openedAt = time.Now()
+			logWarning("Circuit breaker for %s is open after %d consecutive failures, last error: %s", hostname, h.failures, h.lastError)
+		}
+	}
+}
+
+// RetryAfter 返回主机预计进入半开状态前的剩余时间
+func (b *Breaker) RetryAfter(hostname string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h, ok := b.hosts[hostname]
+	if !ok || h.state != StateOpen {
+		return 0
+	}
+	remain := b.openTimeout - time.Since(h.openedAt)
+	if remain < 0 {
+		return 0
+	}
+	return remain
+}
+
+// States 返回所有已知主机的熔断状态, 按主机名排序
+func (b *Breaker) States() []HostState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	states := make([]HostState, 0, len(b.hosts))
+	for name, h := range b.hosts {
+		s := HostState{
+			Host:         name,
+			State:        h.state,
+			Failures:     h.failures,
+			LastError:    h.lastError,
+			TotalSuccess: h.totalSuccess,
+			TotalFailure: h.totalFailure,
+			TotalReject:  h.totalReject,
+		}
+		if h.state != StateClosed {
+			s.OpenedAt = h.openedAt
+			s.RetryAt = h.openedAt.Add(b.openTimeout)
+		}
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Host < states[j].Host
+	})
+	return states
+}
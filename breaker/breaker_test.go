@@ -0,0 +1,157 @@
+package breaker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "breaker-test")
+	if err != nil {
+		panic(err)
+	}
+	logger.Init(filepath.Join(dir, "test.log"), 1)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestHalfOpenProbes(t *testing.T) {
+	const hostname = "github.com"
+	failure := errors.New("upstream failed")
+
+	type step struct {
+		op     string // allow: 申请令牌; report: 报告第 idx 个令牌的结果; wait: 等待进入半开
+		idx    int
+		result Result
+		want   error // allow 的期望返回
+	}
+	tests := []struct {
+		name   string
+		probes int
+		steps  []step
+		state  State
+	}{
+		{
+			name: "late closed request does not release probe",
+			steps: []step{
+				{op: "allow"}, // 0: 熔断关闭时放行
+				{op: "allow"}, // 1: 触发熔断的请求
+				{op: "report", idx: 1, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},                // 2: 探测请求
+				{op: "allow", want: ErrOpen}, // 名额已满
+				{op: "report", idx: 0, result: ResultIgnore},
+				{op: "allow", want: ErrOpen}, // 迟到的结果不应释放名额
+			},
+			state: StateHalfOpen,
+		},
+		{
+			name: "late closed success does not close",
+			steps: []step{
+				{op: "allow"},
+				{op: "allow"},
+				{op: "report", idx: 1, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultSuccess},
+			},
+			state: StateHalfOpen,
+		},
+		{
+			name: "late closed failure does not reopen",
+			steps: []step{
+				{op: "allow"},
+				{op: "allow"},
+				{op: "report", idx: 1, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultFailure},
+			},
+			state: StateHalfOpen,
+		},
+		{
+			name: "probe success closes",
+			steps: []step{
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},
+				{op: "report", idx: 1, result: ResultSuccess},
+			},
+			state: StateClosed,
+		},
+		{
+			name: "probe failure reopens",
+			steps: []step{
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},
+				{op: "report", idx: 1, result: ResultFailure},
+				{op: "allow", want: ErrOpen},
+			},
+			state: StateOpen,
+		},
+		{
+			name: "ignored probe releases slot",
+			steps: []step{
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"},
+				{op: "report", idx: 1, result: ResultIgnore},
+				{op: "allow"},
+			},
+			state: StateHalfOpen,
+		},
+		{
+			name:   "stale probe from previous half-open",
+			probes: 2,
+			steps: []step{
+				{op: "allow"},
+				{op: "report", idx: 0, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"}, // 1: 第一轮探测, 结果迟到
+				{op: "allow"}, // 2: 第一轮探测
+				{op: "report", idx: 2, result: ResultFailure},
+				{op: "wait"},
+				{op: "allow"}, // 3: 第二轮探测
+				{op: "allow"}, // 4: 第二轮探测
+				{op: "report", idx: 1, result: ResultSuccess},
+				{op: "allow", want: ErrOpen},
+			},
+			state: StateHalfOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(1, 10*time.Millisecond, tt.probes)
+			var tokens []Token
+			for i, s := range tt.steps {
+				switch s.op {
+				case "allow":
+					token, err := b.Allow(hostname)
+					if !errors.Is(err, s.want) {
+						t.Fatalf("step %d: Allow() = %v, want %v", i, err, s.want)
+					}
+					if err == nil {
+						tokens = append(tokens, token)
+					}
+				case "report":
+					b.Report(hostname, tokens[s.idx], s.result, failure)
+				case "wait":
+					time.Sleep(15 * time.Millisecond)
+				}
+			}
+			if got := b.States()[0].State; got != tt.state {
+				t.Fatalf("state = %s, want %s", got, tt.state)
+			}
+		})
+	}
+}
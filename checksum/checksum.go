@@ -0,0 +1,202 @@
+package checksum
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrMismatch 校验不一致
+var ErrMismatch = errors.New("sha256 mismatch")
+
+// Result 校验结果
+type Result string
+
+const (
+	ResultUnverified Result = "unverified" // 无预期摘要
+	ResultVerified   Result = "verified"   // 与预期摘要一致
+	ResultMismatch   Result = "mismatch"   // 与预期摘要不一致
+)
+
+// ParseSums 从校验文件中查找文件名对应的 SHA-256
+// 支持 sha256sum 格式 "hash  name" / "hash *name", BSD 格式 "SHA256 (name) = hash",
+// 以及仅包含一个摘要的 *.sha256 文件
+func ParseSums(data []byte, filename string) (string, bool) {
+	var (
+		only  string
+		lines int
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines++
+
+		if rest, ok := strings.CutPrefix(line, "SHA256 ("); ok {
+			name, sum, ok := strings.Cut(rest, ") = ")
+			if ok && path.Base(name) == filename && IsHex(sum) {
+				return strings.ToLower(sum), true
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !IsHex(fields[0]) {
+			continue
+		}
+		if len(fields) == 1 {
+			only = fields[0]
+			continue
+		}
+		name := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		if path.Base(name) == filename {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	if lines == 1 && only != "" {
+		return strings.ToLower(only), true
+	}
+	return "", false
+}
+
+// IsHex 判断是否为 SHA-256 的十六进制表示
+func IsHex(s string) bool {
+	if len(s) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// Reader 边读取边计算 SHA-256, 读取到末尾或读满已知长度时与预期摘要比较
+// enforce 为 true 时保留最后一个字节, 校验一致后才交付, 不一致时以 ErrMismatch 结束读取,
+// 客户端收到的内容比原文件少一个字节, 不会得到完整的错误文件
+type Reader struct {
+	body     io.ReadCloser
+	hash     hash.Hash
+	expected string
+	size     int64 // 为 -1 表示长度未知, 以 EOF 为结束
+	read     int64
+	enforce  bool
+	onDone   func(r *Reader)
+
+	pending    byte
+	hasPending bool
+	done       bool
+	err        error
+	sum        string
+	result     Result
+}
+
+// NewReader 创建校验读取器, expected 为空时仅计算摘要, onDone 在读取到末尾时调用一次
+// size 为响应体长度, 已知时读满即完成校验, 上层按长度读取时不会再读到 EOF
+func NewReader(body io.ReadCloser, expected string, size int64, enforce bool, onDone func(r *Reader)) *Reader {
+	return &Reader{
+		body:     body,
+		hash:     sha256.New(),
+		expected: strings.ToLower(expected),
+		size:     size,
+		enforce:  enforce && expected != "",
+		onDone:   onDone,
+		result:   ResultUnverified,
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.done {
+		return r.flush(p)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		n, err := r.body.Read(p)
+		r.hash.Write(p[:n])
+		r.read += int64(n)
+		if r.enforce && n > 0 {
+			n = r.holdBack(p, n)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return n, err
+		}
+		if err != nil || r.size >= 0 && r.read >= r.size {
+			r.finish()
+			if n > 0 {
+				return n, nil
+			}
+			return r.flush(p)
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// holdBack 输出延后一个字节, 始终保留当前读到的最后一个字节
+func (r *Reader) holdBack(p []byte, n int) int {
+	last := p[n-1]
+	if r.hasPending {
+		copy(p[1:n], p[:n-1])
+		p[0] = r.pending
+		r.pending = last
+		return n
+	}
+	r.pending = last
+	r.hasPending = true
+	return n - 1
+}
+
+// flush 读取结束后交付保留的字节, 校验不一致时返回 ErrMismatch
+func (r *Reader) flush(p []byte) (int, error) {
+	if r.result == ResultMismatch && r.enforce {
+		return 0, ErrMismatch
+	}
+	if r.hasPending && len(p) > 0 {
+		p[0] = r.pending
+		r.hasPending = false
+		return 1, nil
+	}
+	return 0, io.EOF
+}
+
+func (r *Reader) finish() {
+	r.done = true
+	r.sum = hex.EncodeToString(r.hash.Sum(nil))
+	if r.expected != "" {
+		if r.sum == r.expected {
+			r.result = ResultVerified
+		} else {
+			r.result = ResultMismatch
+		}
+	}
+	if r.onDone != nil {
+		r.onDone(r)
+	}
+}
+
+// Sum 返回十六进制摘要, 读取结束前为空
+func (r *Reader) Sum() string {
+	return r.sum
+}
+
+// Expected 返回预期摘要
+func (r *Reader) Expected() string {
+	return r.expected
+}
+
+// Result 返回校验结果
+func (r *Reader) Result() Result {
+	return r.result
+}
+
+func (r *Reader) Close() error {
+	return r.body.Close()
+}
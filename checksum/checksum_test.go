@@ -0,0 +1,73 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+// noEOFBody 读完数据后不再返回 EOF, 模拟仅按 Content-Length 读取的上层
+type noEOFBody struct {
+	r *bytes.Reader
+}
+
+func (b *noEOFBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, errors.New("read past content length")
+	}
+	return b.r.Read(p)
+}
+
+func (b *noEOFBody) Close() error { return nil }
+
+func TestReaderKnownSize(t *testing.T) {
+	data := []byte("release asset content")
+	sum := sha256.Sum256(data)
+	good := hex.EncodeToString(sum[:])
+	bad := hex.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		name     string
+		body     io.ReadCloser
+		size     int64
+		expected string
+		enforce  bool
+		want     []byte
+		wantErr  error
+		result   Result
+	}{
+		{"verified without EOF", &noEOFBody{r: bytes.NewReader(data)}, int64(len(data)), good, true, data, nil, ResultVerified},
+		{"enforced mismatch without EOF", &noEOFBody{r: bytes.NewReader(data)}, int64(len(data)), bad, true, data[:len(data)-1], ErrMismatch, ResultMismatch},
+		{"logged mismatch without EOF", &noEOFBody{r: bytes.NewReader(data)}, int64(len(data)), bad, false, data, nil, ResultMismatch},
+		{"unverified without EOF", &noEOFBody{r: bytes.NewReader(data)}, int64(len(data)), "", true, data, nil, ResultUnverified},
+		{"unknown size until EOF", io.NopCloser(bytes.NewReader(data)), -1, good, true, data, nil, ResultVerified},
+		{"empty body until EOF", io.NopCloser(bytes.NewReader(nil)), -1, "", false, []byte{}, nil, ResultUnverified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := 0
+			r := NewReader(tt.body, tt.expected, tt.size, tt.enforce, func(*Reader) { done++ })
+			var src io.Reader = r
+			if tt.size >= 0 {
+				// 与 hertz 按 Content-Length 发送响应体的方式一致
+				src = io.LimitReader(r, tt.size)
+			}
+			got, err := io.ReadAll(src)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("read %q, want %q", got, tt.want)
+			}
+			if done != 1 || r.Result() != tt.result {
+				t.Fatalf("onDone calls = %d, result = %s; want 1, %s", done, r.Result(), tt.result)
+			}
+			if tt.result != ResultUnverified && r.Sum() != good {
+				t.Fatalf("Sum() = %s, want %s", r.Sum(), good)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// copyBufferSize 上游复制缓冲区大小
+const copyBufferSize = 32 * 1024
+
+// FetchFunc 执行上游请求, ctx 与任何单个客户端的生命周期无关
+type FetchFunc func(ctx context.Context) (*http.Response, error)
+
+// Group 合并相同 key 的并发请求, 仅向上游发起一次请求
+type Group struct {
+	mu       sync.Mutex
+	flights  map[string]*flight
+	spillDir string
+}
+
+// New 创建请求合并组, spillDir 为空时使用系统临时目录
+func New(spillDir string) *Group {
+	if spillDir == "" {
+		spillDir = os.TempDir()
+	}
+	return &Group{
+		flights:  make(map[string]*flight),
+		spillDir: spillDir,
+	}
+}
+
+// flight 一次进行中的上游请求, 响应体写入溢出文件供所有等待者读取
+type flight struct {
+	group  *Group
+	key    string
+	cancel context.CancelFunc
+
+	ready chan struct{} // 响应头就绪或请求失败时关闭
+	resp  *http.Response
+	err   error
+
+	mu       sync.Mutex
+	file     *os.File
+	written  int64
+	done     bool
+	readErr  error
+	notify   chan struct{} // 每次写入进度更新时关闭并替换
+	refs     int
+	released bool
+}
+
+// Do 执行或加入一次合并请求
+// 返回的响应体从溢出文件读取, 调用者必须关闭; leader 表示本次调用是否为实际发起上游请求的一方
+func (g *Group) Do(ctx context.Context, key string, fetch FetchFunc) (resp *http.Response, leader bool, err error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if ok {
+		f.mu.Lock()
+		f.refs++
+		f.mu.Unlock()
+		g.mu.Unlock()
+		logDebug("Coalesce join: %s", key)
+	} else {
+		fetchCtx, cancel := context.WithCancel(context.Background())
+		f = &flight{
+			group:  g,
+			key:    key,
+			cancel: cancel,
+			ready:  make(chan struct{}),
+			notify: make(chan struct{}),
+			refs:   1,
+		}
+		g.flights[key] = f
+		g.mu.Unlock()
+		leader = true
+		go f.run(fetchCtx, fetch)
+	}
+
+	select {
+	case <-f.ready:
+	case <-ctx.Done():
+		f.release()
+		return nil, leader, ctx.Err()
+	}
+
+	if f.err != nil {
+		f.release()
+		return nil, leader, f.err
+	}
+
+	// 复制响应, 每个等待者拥有独立的响应头与读取器
+	r := new(http.Response)
+	*r = *f.resp
+	r.Header = f.resp.Header.Clone()
+	r.Body = &reader{f: f, ctx: ctx}
+	return r, leader, nil
+}
+
+// run 发起上游请求并将响应体写入溢出文件
+func (f *flight) run(ctx context.Context, fetch FetchFunc) {
+	resp, err := fetch(ctx)
+	if err == nil {
+		f.file, err = os.CreateTemp(f.group.spillDir, "ghproxy-coalesce-*")
+		if err != nil {
+			resp.Body.Close()
+		}
+	}
+	if err != nil {
+		f.err = err
+		f.finish(err)
+		close(f.ready)
+		return
+	}
+
+	body := resp.Body
+	resp.Body = nil
+	f.resp = resp
+	close(f.ready)
+
+	buf := make([]byte, copyBufferSize)
+	var readErr error
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, werr := f.file.Write(buf[:n]); werr != nil {
+				readErr = werr
+				break
+			}
+			f.mu.Lock()
+			f.written += int64(n)
+			close(f.notify)
+			f.notify = make(chan struct{})
+			f.mu.Unlock()
+		}
+		if rerr != nil {
+			if !errors.Is(rerr, io.EOF) {
+				readErr = rerr
+			}
+			break
+		}
+	}
+	body.Close()
+	f.finish(readErr)
+}
+
+// finish 标记上游读取结束并从组中移除, 新的请求将重新发起
+func (f *flight) finish(err error) {
+	f.group.mu.Lock()
+	if f.group.flights[f.key] == f {
+		delete(f.group.flights, f.key)
+	}
+	f.group.mu.Unlock()
+
+	f.mu.Lock()
+	f.done = true
+	f.readErr = err
+	close(f.notify)
+	f.notify = make(chan struct{})
+	cleanup := f.refs == 0
+	f.mu.Unlock()
+
+	if err != nil {
+		logDebug("Coalesce fetch %s finished with error: %v", f.key, err)
+	}
+	if cleanup {
+		f.cleanup()
+	}
+}
+
+// release 释放一个等待者的引用, 最后一个等待者离开时取消上游请求并清理溢出文件
+func (f *flight) release() {
+	f.group.mu.Lock()
+	f.mu.Lock()
+	f.refs--
+	last := f.refs == 0
+	done := f.done
+	if last && f.group.flights[f.key] == f {
+		// 避免新的请求加入即将被取消的 flight
+		delete(f.group.flights, f.key)
+	}
+	f.mu.Unlock()
+	f.group.mu.Unlock()
+
+	if !last {
+		return
+	}
+	if !done {
+		// 所有客户端都已断开, 没有必要继续拉取
+		f.cancel()
+		return
+	}
+	f.cleanup()
+}
+
+func (f *flight) cleanup() {
+	f.mu.Lock()
+	if f.released {
+		f.mu.Unlock()
+		return
+	}
+	f.released = true
+	file := f.file
+	f.mu.Unlock()
+
+	f.cancel()
+	if file != nil {
+		name := file.Name()
+		file.Close()
+		os.Remove(name)
+	}
+}
+
+// reader 从溢出文件读取, 追上写入进度后等待新数据
+type reader struct {
+	f         *flight
+	ctx       context.Context
+	off       int64
+	closeOnce sync.Once
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		r.f.mu.Lock()
+		written := r.f.written
+		done := r.f.done
+		readErr := r.f.readErr
+		notify := r.f.notify
+		file := r.f.file
+		r.f.mu.Unlock()
+
+		if r.off < written {
+			if remain := written - r.off; int64(len(p)) > remain {
+				p = p[:remain]
+			}
+			n, err := file.ReadAt(p, r.off)
+			r.off += int64(n)
+			if err != nil && !errors.Is(err, io.EOF) {
+				return n, err
+			}
+			return n, nil
+		}
+		if done {
+			if readErr != nil {
+				return 0, readErr
+			}
+			return 0, io.EOF
+		}
+
+		select {
+		case <-notify:
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+	}
+}
+
+func (r *reader) Close() error {
+	r.closeOnce.Do(r.f.release)
+	return nil
+}
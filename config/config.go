@@ -7,18 +7,32 @@ import (
 )
 
 type Config struct {
-	Server    ServerConfig
-	Httpc     HttpcConfig
-	GitClone  GitCloneConfig
-	Shell     ShellConfig
-	Pages     PagesConfig
-	Log       LogConfig
-	Auth      AuthConfig
-	Blacklist BlacklistConfig
-	Whitelist WhitelistConfig
-	RateLimit RateLimitConfig
-	Outbound  OutboundConfig
-	Docker    DockerConfig
+	Server        ServerConfig
+	Httpc         HttpcConfig
+	GitClone      GitCloneConfig
+	Shell         ShellConfig
+	Pages         PagesConfig
+	Log           LogConfig
+	Auth          AuthConfig
+	Blacklist     BlacklistConfig
+	Whitelist     WhitelistConfig
+	RateLimit     RateLimitConfig
+	Outbound      OutboundConfig
+	Docker        DockerConfig
+	Admin         AdminConfig
+	Ban           BanConfig
+	Cache         CacheConfig
+	Coalesce      CoalesceConfig
+	APICache      APICacheConfig
+	TokenPool     TokenPoolConfig
+	APIRewrite    APIRewriteConfig
+	APIPolicy     APIPolicyConfig
+	Headers       HeadersConfig
+	Redirect      RedirectConfig
+	OverLimit     OverLimitConfig
+	ContentPolicy ContentPolicyConfig
+	Checksum      ChecksumConfig
+	Upstreams     []UpstreamConfig
 }
 
 /*
@@ -52,13 +66,97 @@ maxIdleConns = 100 # only for advanced mode
 maxIdleConnsPerHost = 60 # only for advanced mode
 maxConnsPerHost = 0 # only for advanced mode
 useCustomRawHeaders = false
+
+	[httpc.retry]
+	enabled = false
+	maxAttempts = 3 # 首字节前的最大重试次数, 仅GET/HEAD
+	baseDelay = 200 # ms
+	maxDelay = 3000 # ms
+	resume = true # 上游支持Range时, 传输中断后自动续传
+	maxResumes = 3
+
+	[httpc.breaker]
+	enabled = false
+	failureThreshold = 5 # 连续失败次数达到阈值后熔断
+	openTimeout = 30 # s, 熔断后经过该时长进入半开状态
+	halfOpenProbes = 1 # 半开状态下允许的并发探测请求数
+
+	[httpc.segmented]
+	enabled = false
+	threshold = 64 # MB, 超过该大小的 release 资源以多个 Range 请求并发下载
+	concurrency = 4 # 单个下载同时下载或缓存的分段数
+	segmentSize = 4 # MB, 单个分段大小, 单个下载占用内存上限为 concurrency*segmentSize
+	maxActive = 16 # 同时进行的分段下载数上限, 超出时按普通方式传输
+
+	[httpc.timeouts]
+	dial = 10 # s, 0 使用默认值
+	tlsHandshake = 10 # s, 0 使用默认值
+	responseHeader = 30 # s, 0 不限制
+	expectContinue = 1 # s, 0 使用默认值
+	idleRead = 60 # s, 单次读取上游响应体的最长等待时间, 0 不限制
+
+	[httpc.dns]
+	enabled = false
+	servers = ["https://1.1.1.1/dns-query", "tls://8.8.8.8:853", "223.5.5.5"] # 按顺序尝试, 为空时使用系统解析器
+	preferIP = "" # "ipv4" / "ipv6" / "" 不调整顺序
+	cacheTTL = 300 # s, 缓存时长上限
+	timeout = 5 # s, 单个服务器的查询超时
+
+	[httpc.dns.hosts]
+	"github.com" = "140.82.112.3"
 */
 type HttpcConfig struct {
-	Mode                string `toml:"mode"`
-	MaxIdleConns        int    `toml:"maxIdleConns"`
-	MaxIdleConnsPerHost int    `toml:"maxIdleConnsPerHost"`
-	MaxConnsPerHost     int    `toml:"maxConnsPerHost"`
-	UseCustomRawHeaders bool   `toml:"useCustomRawHeaders"`
+	Mode                string          `toml:"mode"`
+	MaxIdleConns        int             `toml:"maxIdleConns"`
+	MaxIdleConnsPerHost int             `toml:"maxIdleConnsPerHost"`
+	MaxConnsPerHost     int             `toml:"maxConnsPerHost"`
+	UseCustomRawHeaders bool            `toml:"useCustomRawHeaders"`
+	Retry               RetryConfig     `toml:"retry"`
+	Breaker             BreakerConfig   `toml:"breaker"`
+	Segmented           SegmentedConfig `toml:"segmented"`
+	Timeouts            TimeoutsConfig  `toml:"timeouts"`
+	DNS                 DNSConfig       `toml:"dns"`
+}
+
+type RetryConfig struct {
+	Enabled     bool `toml:"enabled"`
+	MaxAttempts int  `toml:"maxAttempts"`
+	BaseDelay   int  `toml:"baseDelay"`
+	MaxDelay    int  `toml:"maxDelay"`
+	Resume      bool `toml:"resume"`
+	MaxResumes  int  `toml:"maxResumes"`
+}
+
+type BreakerConfig struct {
+	Enabled          bool `toml:"enabled"`
+	FailureThreshold int  `toml:"failureThreshold"`
+	OpenTimeout      int  `toml:"openTimeout"`
+	HalfOpenProbes   int  `toml:"halfOpenProbes"`
+}
+
+type SegmentedConfig struct {
+	Enabled     bool `toml:"enabled"`
+	Threshold   int  `toml:"threshold"`
+	Concurrency int  `toml:"concurrency"`
+	SegmentSize int  `toml:"segmentSize"`
+	MaxActive   int  `toml:"maxActive"`
+}
+
+type TimeoutsConfig struct {
+	Dial           int `toml:"dial"`
+	TLSHandshake   int `toml:"tlsHandshake"`
+	ResponseHeader int `toml:"responseHeader"`
+	ExpectContinue int `toml:"expectContinue"`
+	IdleRead       int `toml:"idleRead"`
+}
+
+type DNSConfig struct {
+	Enabled  bool              `toml:"enabled"`
+	Servers  []string          `toml:"servers"`
+	PreferIP string            `toml:"preferIP"`
+	CacheTTL int               `toml:"cacheTTL"`
+	Timeout  int               `toml:"timeout"`
+	Hosts    map[string]string `toml:"hosts"`
 }
 
 /*
@@ -89,12 +187,19 @@ mode = "internal" # "internal" or "external"
 theme = "bootstrap" # "bootstrap" or "nebula"
 staticDir = "/data/www"
 custom404 = "" # 自定义404页面路径，为空则使用内置404页面
+language = "zh-CN" # 错误信息的默认语言, 无法按 Accept-Language 匹配时使用
+localesDir = "" # 覆盖或新增错误信息翻译的目录, 文件名为语言标签, 如 en.toml
+[pages.helpInfo] # 按状态码替换帮助信息, 适用于所有语言
+"403" = "如有疑问请参阅 https://wiki.example.com/ghproxy"
 */
 type PagesConfig struct {
-	Mode      string `toml:"mode"`
-	Theme     string `toml:"theme"`
-	StaticDir string `toml:"staticDir"`
-	Custom404 string `toml:"custom404"`
+	Mode       string            `toml:"mode"`
+	Theme      string            `toml:"theme"`
+	StaticDir  string            `toml:"staticDir"`
+	Custom404  string            `toml:"custom404"`
+	Language   string            `toml:"language"`
+	LocalesDir string            `toml:"localesDir"`
+	HelpInfo   map[string]string `toml:"helpInfo"`
 }
 
 type LogConfig struct {
@@ -114,15 +219,25 @@ enabled = false
 passThrough = false
 ForceAllowApi = false
 ForceAllowApiPassList = false
+
+[[auth.tokens]]
+token = "" # 与 token 一样可通过鉴权, 并额外授予 scopes
+scopes = ["api:write"]
 */
 type AuthConfig struct {
-	Enabled               bool   `toml:"enabled"`
-	Method                string `toml:"method"`
-	Key                   string `toml:"key"`
-	Token                 string `toml:"token"`
-	PassThrough           bool   `toml:"passThrough"`
-	ForceAllowApi         bool   `toml:"ForceAllowApi"`
-	ForceAllowApiPassList bool   `toml:"ForceAllowApiPassList"`
+	Enabled               bool        `toml:"enabled"`
+	Method                string      `toml:"method"`
+	Key                   string      `toml:"key"`
+	Token                 string      `toml:"token"`
+	PassThrough           bool        `toml:"passThrough"`
+	ForceAllowApi         bool        `toml:"ForceAllowApi"`
+	ForceAllowApiPassList bool        `toml:"ForceAllowApiPassList"`
+	Tokens                []AuthToken `toml:"tokens"`
+}
+
+type AuthToken struct {
+	Token  string   `toml:"token"`
+	Scopes []string `toml:"scopes"`
 }
 
 type BlacklistConfig struct {
@@ -169,10 +284,31 @@ type BandwidthLimitConfig struct {
 [outbound]
 enabled = false
 url = "socks5://127.0.0.1:1080" # "http://127.0.0.1:7890"
+pool = [] # ["http://127.0.0.1:7890", "socks5h://127.0.0.1:1080"], 非空时替代url
+strategy = "round-robin" # "round-robin" / "least-conn" / "failover"
+healthCheckUrl = "https://github.com"
+healthCheckInterval = 30 # s, 0 不进行主动健康检查
+healthCheckTimeout = 5 # s
+maxFails = 3 # 连续失败次数达到阈值后移出轮换, 恢复后自动加入
+sourceAddrs = [] # ["203.0.113.10", "203.0.113.11"], 出站连接绑定的本机源地址, 不受enabled影响
+sourceStrategy = "round-robin" # "round-robin" / "hash"
+
+	[outbound.routes]
+	docker = "http://127.0.0.1:7890" # "direct" / "pool" / 代理URL
+	clone = "direct"
 */
 type OutboundConfig struct {
-	Enabled bool   `toml:"enabled"`
-	Url     string `toml:"url"`
+	Enabled             bool              `toml:"enabled"`
+	Url                 string            `toml:"url"`
+	Pool                []string          `toml:"pool"`
+	Strategy            string            `toml:"strategy"`
+	HealthCheckUrl      string            `toml:"healthCheckUrl"`
+	HealthCheckInterval int               `toml:"healthCheckInterval"`
+	HealthCheckTimeout  int               `toml:"healthCheckTimeout"`
+	MaxFails            int               `toml:"maxFails"`
+	SourceAddrs         []string          `toml:"sourceAddrs"`
+	SourceStrategy      string            `toml:"sourceStrategy"`
+	Routes              map[string]string `toml:"routes"`
 }
 
 /*
@@ -185,6 +321,227 @@ type DockerConfig struct {
 	Target  string `toml:"target"`
 }
 
+/*
+[admin]
+enabled = false
+token = ""
+persist = false # 是否将运行时修改写回配置文件
+*/
+type AdminConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Token   string `toml:"token"`
+	Persist bool   `toml:"persist"`
+}
+
+/*
+[ban]
+enabled = false
+window = 60 # 秒, 统计窗口
+banDuration = 300 # 秒, 首次封禁时长, 之后每次翻倍
+maxBanDuration = 86400 # 秒, 最长封禁时长
+offenseDecay = 604800 # 秒, 封禁到期后无新封禁满该时长则清零封禁次数, 0为永不清零
+rateLimitThreshold = 30 # 窗口内429次数, 0为不统计
+authThreshold = 10 # 窗口内401次数
+listThreshold = 20 # 窗口内黑白名单403次数
+invalidURLThreshold = 20 # 窗口内无效URL 400次数
+persistFile = "/data/ghproxy/config/bans.json"
+*/
+type BanConfig struct {
+	Enabled             bool   `toml:"enabled"`
+	Window              int    `toml:"window"`
+	BanDuration         int    `toml:"banDuration"`
+	MaxBanDuration      int    `toml:"maxBanDuration"`
+	OffenseDecay        int    `toml:"offenseDecay"`
+	RateLimitThreshold  int    `toml:"rateLimitThreshold"`
+	AuthThreshold       int    `toml:"authThreshold"`
+	ListThreshold       int    `toml:"listThreshold"`
+	InvalidURLThreshold int    `toml:"invalidURLThreshold"`
+	PersistFile         string `toml:"persistFile"`
+}
+
+/*
+[cache]
+enabled = false
+dir = "/data/ghproxy/cache"
+maxSize = 10240 # MB
+ttl = 1440 # 分钟, 不可变的release资源不受此限制
+*/
+type CacheConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Dir     string `toml:"dir"`
+	MaxSize int    `toml:"maxSize"`
+	TTL     int    `toml:"ttl"`
+}
+
+/*
+[coalesce]
+enabled = false
+spillDir = "" # 为空则使用系统临时目录
+*/
+type CoalesceConfig struct {
+	Enabled  bool   `toml:"enabled"`
+	SpillDir string `toml:"spillDir"`
+}
+
+/*
+[apiCache]
+enabled = false
+ttl = 60 # s, 有效期内直接以缓存响应, 过期后以 If-None-Match/If-Modified-Since 向上游重新验证
+maxSize = 64 # MB, 内存占用上限, 超出时淘汰最久未使用的条目
+maxEntrySize = 1024 # KB, 单个响应的大小上限
+*/
+type APICacheConfig struct {
+	Enabled      bool `toml:"enabled"`
+	TTL          int  `toml:"ttl"`
+	MaxSize      int  `toml:"maxSize"`
+	MaxEntrySize int  `toml:"maxEntrySize"`
+}
+
+/*
+[tokenPool]
+enabled = false
+tokens = [] # GitHub PAT / App 安装令牌, "env:NAME" 表示从环境变量读取
+matchers = ["api", "raw", "releases"] # 注入令牌的matcher, 仅对 github.com / api.github.com / raw.githubusercontent.com 生效
+quarantine = 600 # s, 令牌被上游拒绝(401)后的隔离时长
+*/
+type TokenPoolConfig struct {
+	Enabled    bool     `toml:"enabled"`
+	Tokens     []string `toml:"tokens"`
+	Matchers   []string `toml:"matchers"`
+	Quarantine int      `toml:"quarantine"`
+}
+
+/*
+[apiRewrite]
+enabled = false
+link = true # 改写分页 Link 响应头
+fields = ["url", "browser_download_url", "tarball_url", "zipball_url", "clone_url", "download_url"] # 为空则改写所有字段
+maxSize = 1024 # KB, 超出时原样转发
+*/
+type APIRewriteConfig struct {
+	Enabled bool     `toml:"enabled"`
+	Link    bool     `toml:"link"`
+	Fields  []string `toml:"fields"`
+	MaxSize int      `toml:"maxSize"`
+}
+
+/*
+[apiPolicy]
+graphqlReadQueries = true # 仅含 query 的 GraphQL 请求按 GET 的策略处理, 含 mutation 的按 POST 处理
+
+[apiPolicy.methods] # 方法 -> 所需 scope, "" 表示无需 scope, "-" 表示禁止, 未列出的方法返回 405
+GET = ""
+HEAD = ""
+POST = "api:write"
+PATCH = "api:write"
+PUT = "api:write"
+DELETE = "api:write"
+*/
+type APIPolicyConfig struct {
+	GraphQLReadQueries bool              `toml:"graphqlReadQueries"`
+	Methods            map[string]string `toml:"methods"`
+}
+
+/*
+[[headers.rules]]
+matchers = ["releases", "raw"] # 为空则作用于所有matcher
+direction = "response" # "request" 发往上游 / "response" 返回客户端
+remove = ["Set-Cookie"]
+rename = { "X-Old" = "X-New" }
+set = { "Content-Disposition" = "attachment; filename=\"${filename}\"" }
+add = { "Via" = "1.1 ghproxy" }
+# 可用变量: ${client_ip} ${request_id} ${matcher} ${filename}
+*/
+type HeadersConfig struct {
+	Rules []HeaderRule `toml:"rules"`
+}
+
+type HeaderRule struct {
+	Matchers  []string          `toml:"matchers"`
+	Direction string            `toml:"direction"`
+	Remove    []string          `toml:"remove"`
+	Rename    map[string]string `toml:"rename"`
+	Set       map[string]string `toml:"set"`
+	Add       map[string]string `toml:"add"`
+}
+
+/*
+[redirect]
+enabled = false
+mode = "rewrite" # "follow" 服务端跟随 / "rewrite" 改写 Location 指向本代理
+allowedHosts = ["github.com", "codeload.github.com", "objects.githubusercontent.com", "release-assets.githubusercontent.com", "raw.githubusercontent.com", "gist.githubusercontent.com"]
+*/
+type RedirectConfig struct {
+	Enabled      bool     `toml:"enabled"`
+	Mode         string   `toml:"mode"`
+	AllowedHosts []string `toml:"allowedHosts"`
+}
+
+/*
+[overLimit]
+action = "redirect" # 超出 server.sizeLimit 时的动作: "redirect" / "reject" / "allow-for-authenticated"
+[overLimit.matchers] # 按 matcher 覆盖, 可选 releases archive codeload objects patch blob raw gist api clone docker
+clone = "reject"
+*/
+type OverLimitConfig struct {
+	Action   string            `toml:"action"`
+	Matchers map[string]string `toml:"matchers"`
+}
+
+/*
+[[contentPolicy.rules]]
+name = "no-windows-binaries"
+matchers = ["releases"] # 为空则作用于所有matcher
+action = "deny" # "deny" / "allow", 配置了 allow 规则的 matcher 仅放行命中 allow 规则的内容
+extensions = [".exe", ".msi", ".apk"]
+contentTypes = ["application/vnd.android.package-archive"] # 支持 "text/*" 通配
+*/
+type ContentPolicyConfig struct {
+	Rules []ContentRule `toml:"rules"`
+}
+
+type ContentRule struct {
+	Name         string   `toml:"name"`
+	Matchers     []string `toml:"matchers"`
+	Action       string   `toml:"action"`
+	Extensions   []string `toml:"extensions"`
+	ContentTypes []string `toml:"contentTypes"`
+}
+
+/*
+[checksum]
+enabled = false
+trailer = true # 以 trailer 返回 SHA-256, 响应改用 chunked 传输
+verify = "off" # "off" / "log" / "enforce"
+checksumFiles = ["{file}.sha256", "checksums.txt", "SHA256SUMS", "sha256sums.txt"]
+*/
+type ChecksumConfig struct {
+	Enabled       bool     `toml:"enabled"`
+	Trailer       bool     `toml:"trailer"`
+	Verify        string   `toml:"verify"`
+	ChecksumFiles []string `toml:"checksumFiles"`
+}
+
+/*
+[[upstreams]]
+name = "gitea"
+hosts = ["git.example.com"] # 支持 "*.example.com" 通配
+forge = "" # "gitlab" / "gitea" / "forgejo" / "bitbucket", 设置后使用平台内置的路径解析, 忽略 path matcher client
+path = "/:user/:repo/*" # :user :repo 提取对应路径段用于黑白名单, 末尾的 * 匹配任意剩余路径
+matcher = "raw" # 用于 headers/overLimit/contentPolicy 等按 matcher 生效的配置
+client = "proxy" # "proxy" / "git"
+shell = false # 是否改写 shell 脚本中指向该上游的链接, 并改写该上游返回的 .sh 脚本
+*/
+type UpstreamConfig struct {
+	Name    string   `toml:"name"`
+	Hosts   []string `toml:"hosts"`
+	Forge   string   `toml:"forge"`
+	Path    string   `toml:"path"`
+	Matcher string   `toml:"matcher"`
+	Client  string   `toml:"client"`
+	Shell   bool     `toml:"shell"`
+}
+
 // LoadConfig 从 TOML 配置文件加载配置
 func LoadConfig(filePath string) (*Config, error) {
 	if !FileExists(filePath) {
@@ -239,6 +596,42 @@ func DefaultConfig() *Config {
 			MaxIdleConns:        100,
 			MaxIdleConnsPerHost: 60,
 			MaxConnsPerHost:     0,
+			Retry: RetryConfig{
+				Enabled:     false,
+				MaxAttempts: 3,
+				BaseDelay:   200,
+				MaxDelay:    3000,
+				Resume:      true,
+				MaxResumes:  3,
+			},
+			Breaker: BreakerConfig{
+				Enabled:          false,
+				FailureThreshold: 5,
+				OpenTimeout:      30,
+				HalfOpenProbes:   1,
+			},
+			Segmented: SegmentedConfig{
+				Enabled:     false,
+				Threshold:   64,
+				Concurrency: 4,
+				SegmentSize: 4,
+				MaxActive:   16,
+			},
+			Timeouts: TimeoutsConfig{
+				Dial:           10,
+				TLSHandshake:   10,
+				ResponseHeader: 30,
+				ExpectContinue: 1,
+				IdleRead:       60,
+			},
+			DNS: DNSConfig{
+				Enabled:  false,
+				Servers:  []string{},
+				PreferIP: "",
+				CacheTTL: 300,
+				Timeout:  5,
+				Hosts:    map[string]string{},
+			},
 		},
 		GitClone: GitCloneConfig{
 			Mode:         "bypass",
@@ -250,9 +643,12 @@ func DefaultConfig() *Config {
 			RewriteAPI: false,
 		},
 		Pages: PagesConfig{
-			Mode:      "internal",
-			Theme:     "aurora",
-			StaticDir: "/data/www",
+			Mode:       "internal",
+			Theme:      "aurora",
+			StaticDir:  "/data/www",
+			Language:   "zh-CN",
+			LocalesDir: "",
+			HelpInfo:   map[string]string{},
 		},
 		Log: LogConfig{
 			LogFilePath:  "/data/ghproxy/log/ghproxy.log",
@@ -268,6 +664,7 @@ func DefaultConfig() *Config {
 			PassThrough:           false,
 			ForceAllowApi:         false,
 			ForceAllowApiPassList: false,
+			Tokens:                []AuthToken{},
 		},
 		Blacklist: BlacklistConfig{
 			Enabled:       false,
@@ -291,12 +688,105 @@ func DefaultConfig() *Config {
 			},
 		},
 		Outbound: OutboundConfig{
-			Enabled: false,
-			Url:     "socks5://127.0.0.1:1080",
+			Enabled:             false,
+			Url:                 "socks5://127.0.0.1:1080",
+			Pool:                []string{},
+			Strategy:            "round-robin",
+			HealthCheckUrl:      "https://github.com",
+			HealthCheckInterval: 30,
+			HealthCheckTimeout:  5,
+			MaxFails:            3,
+			SourceAddrs:         []string{},
+			SourceStrategy:      "round-robin",
+			Routes:              map[string]string{},
 		},
 		Docker: DockerConfig{
 			Enabled: false,
 			Target:  "ghcr",
 		},
+		Admin: AdminConfig{
+			Enabled: false,
+			Token:   "",
+			Persist: false,
+		},
+		Ban: BanConfig{
+			Enabled:             false,
+			Window:              60,
+			BanDuration:         300,
+			MaxBanDuration:      86400,
+			OffenseDecay:        604800,
+			RateLimitThreshold:  30,
+			AuthThreshold:       10,
+			ListThreshold:       20,
+			InvalidURLThreshold: 20,
+			PersistFile:         "/data/ghproxy/config/bans.json",
+		},
+		Cache: CacheConfig{
+			Enabled: false,
+			Dir:     "/data/ghproxy/cache",
+			MaxSize: 10240,
+			TTL:     1440,
+		},
+		Coalesce: CoalesceConfig{
+			Enabled:  false,
+			SpillDir: "",
+		},
+		APICache: APICacheConfig{
+			Enabled:      false,
+			TTL:          60,
+			MaxSize:      64,
+			MaxEntrySize: 1024,
+		},
+		TokenPool: TokenPoolConfig{
+			Enabled:    false,
+			Tokens:     []string{},
+			Matchers:   []string{"api", "raw", "releases"},
+			Quarantine: 600,
+		},
+		APIRewrite: APIRewriteConfig{
+			Enabled: false,
+			Link:    true,
+			Fields:  []string{"url", "browser_download_url", "tarball_url", "zipball_url", "clone_url", "download_url"},
+			MaxSize: 1024,
+		},
+		APIPolicy: APIPolicyConfig{
+			GraphQLReadQueries: true,
+			Methods: map[string]string{
+				"GET":    "",
+				"HEAD":   "",
+				"POST":   "api:write",
+				"PATCH":  "api:write",
+				"PUT":    "api:write",
+				"DELETE": "api:write",
+			},
+		},
+		Headers: HeadersConfig{
+			Rules: []HeaderRule{},
+		},
+		Redirect: RedirectConfig{
+			Enabled: false,
+			Mode:    "rewrite",
+			AllowedHosts: []string{
+				"github.com",
+				"codeload.github.com",
+				"objects.githubusercontent.com",
+				"release-assets.githubusercontent.com",
+				"raw.githubusercontent.com",
+				"gist.githubusercontent.com",
+			},
+		},
+		OverLimit: OverLimitConfig{
+			Action:   "redirect",
+			Matchers: map[string]string{},
+		},
+		ContentPolicy: ContentPolicyConfig{
+			Rules: []ContentRule{},
+		},
+		Checksum: ChecksumConfig{
+			Enabled:       false,
+			Trailer:       true,
+			Verify:        "off",
+			ChecksumFiles: []string{"{file}.sha256", "checksums.txt", "SHA256SUMS", "sha256sums.txt"},
+		},
 	}
 }
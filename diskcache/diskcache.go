@@ -0,0 +1,414 @@
+package diskcache
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+	json "github.com/bytedance/sonic"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// cleanupInterval 后台清理过期项的扫描间隔
+const cleanupInterval = 10 * time.Minute
+
+const (
+	dataSuffix = ".data"
+	metaSuffix = ".json"
+	tmpSuffix  = ".tmp"
+)
+
+// Meta 缓存项元数据, 与数据文件一同落盘
+type Meta struct {
+	Key                string    `json:"key"`        // 最终URL(跟随重定向后)
+	RequestURL         string    `json:"requestURL"` // 客户端请求的URL
+	Size               int64     `json:"size"`
+	ContentType        string    `json:"contentType"`
+	ContentDisposition string    `json:"contentDisposition"`
+	ETag               string    `json:"etag"`
+	LastModified       string    `json:"lastModified"`
+	Immutable          bool      `json:"immutable"` // 不可变资源不受TTL约束
+	CreatedAt          time.Time `json:"createdAt"`
+	LastAccess         time.Time `json:"lastAccess"`
+}
+
+// entry 内存索引项
+type entry struct {
+	meta *Meta
+	name string // 文件名(不含后缀), 为key的sha256
+}
+
+// Cache 基于磁盘的LRU缓存, 带容量上限与TTL
+type Cache struct {
+	mu      sync.Mutex
+	dir     string
+	maxSize int64
+	ttl     time.Duration
+
+	lru     *list.List               // 头部为最近访问
+	entries map[string]*list.Element // key -> element
+	aliases map[string]string        // requestURL -> key
+	curSize int64
+
+	stopCleanup chan struct{}
+	wg          sync.WaitGroup
+}
+
+// New 创建磁盘缓存并从目录中恢复已有的缓存项
+// maxSize: 缓存容量上限(字节), 0 表示无限制
+// ttl: 非不可变资源的有效期, 0 表示不过期
+func New(dir string, maxSize int64, ttl time.Duration) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+
+	c := &Cache{
+		dir:         dir,
+		maxSize:     maxSize,
+		ttl:         ttl,
+		lru:         list.New(),
+		entries:     make(map[string]*list.Element),
+		aliases:     make(map[string]string),
+		stopCleanup: make(chan struct{}),
+	}
+	c.loadIndex()
+
+	c.wg.Add(1)
+	go c.cleanupLoop()
+	return c, nil
+}
+
+// hashKey 计算缓存文件名
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *Cache) dataPath(name string) string {
+	return filepath.Join(c.dir, name+dataSuffix)
+}
+
+func (c *Cache) metaPath(name string) string {
+	return filepath.Join(c.dir, name+metaSuffix)
+}
+
+// loadIndex 扫描缓存目录重建索引, 并清理残留的临时文件
+func (c *Cache) loadIndex() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		logError("Failed to read cache dir: %v", err)
+		return
+	}
+
+	var metas []*entry
+	for _, f := range files {
+		name := f.Name()
+		if strings.HasSuffix(name, tmpSuffix) {
+			os.Remove(filepath.Join(c.dir, name))
+			continue
+		}
+		if !strings.HasSuffix(name, metaSuffix) {
+			continue
+		}
+		base := strings.TrimSuffix(name, metaSuffix)
+		raw, err := os.ReadFile(c.metaPath(base))
+		if err != nil {
+			continue
+		}
+		var meta Meta
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			logWarning("Invalid cache meta %s: %v", name, err)
+			c.removeFiles(base)
+			continue
+		}
+		info, err := os.Stat(c.dataPath(base))
+		if err != nil || info.Size() != meta.Size {
+			c.removeFiles(base)
+			continue
+		}
+		metas = append(metas, &entry{meta: &meta, name: base})
+	}
+
+	// 按最后访问时间从旧到新插入, 保证LRU顺序
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].meta.LastAccess.Before(metas[j].meta.LastAccess)
+	})
+	for _, e := range metas {
+		c.entries[e.meta.Key] = c.lru.PushFront(e)
+		if e.meta.RequestURL != "" {
+			c.aliases[e.meta.RequestURL] = e.meta.Key
+		}
+		c.curSize += e.meta.Size
+	}
+	c.evictIfNeeded()
+	logInfo("Disk cache loaded %d entries, %d bytes", c.lru.Len(), c.curSize)
+}
+
+// expired 判断缓存项是否过期, 需持有锁
+func (c *Cache) expired(meta *Meta, now time.Time) bool {
+	if meta.Immutable || c.ttl <= 0 {
+		return false
+	}
+	return now.Sub(meta.CreatedAt) > c.ttl
+}
+
+// Open 根据请求URL或最终URL查找缓存项, 返回打开的文件与元数据
+func (c *Cache) Open(u string) (*os.File, *Meta, bool) {
+	c.mu.Lock()
+	key := u
+	if alias, ok := c.aliases[u]; ok {
+		key = alias
+	}
+	elem, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, nil, false
+	}
+	e := elem.Value.(*entry)
+	now := time.Now()
+	if c.expired(e.meta, now) {
+		c.removeElement(elem)
+		c.mu.Unlock()
+		return nil, nil, false
+	}
+	c.lru.MoveToFront(elem)
+	e.meta.LastAccess = now
+	meta := *e.meta
+	name := e.name
+	c.mu.Unlock()
+
+	f, err := os.Open(c.dataPath(name))
+	if err != nil {
+		logWarning("Failed to open cache file for %s: %v", key, err)
+		c.mu.Lock()
+		if elem, ok := c.entries[key]; ok {
+			c.removeElement(elem)
+		}
+		c.mu.Unlock()
+		return nil, nil, false
+	}
+	return f, &meta, true
+}
+
+// Tee 包装上游响应体, 在读取的同时写入缓存; 读满 expectedSize 或读到EOF且大小一致时提交, 读取出错时放弃
+// expectedSize 为 -1 时不校验大小; valid 不为 nil 时在提交前调用, 返回 false 时放弃(如摘要校验不一致)
+func (c *Cache) Tee(body io.ReadCloser, meta Meta, expectedSize int64, valid func() bool) io.ReadCloser {
+	name := hashKey(meta.Key)
+	tmp, err := os.CreateTemp(c.dir, name+"-*"+tmpSuffix)
+	if err != nil {
+		logWarning("Failed to create cache temp file: %v", err)
+		return body
+	}
+	return &teeReader{
+		cache:        c,
+		body:         body,
+		tmp:          tmp,
+		meta:         meta,
+		name:         name,
+		expectedSize: expectedSize,
+		valid:        valid,
+	}
+}
+
+// commit 将临时文件提交为缓存项
+func (c *Cache) commit(tmpPath string, name string, meta Meta) error {
+	now := time.Now()
+	meta.CreatedAt = now
+	meta.LastAccess = now
+
+	raw, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 替换已存在的同key缓存项
+	if elem, ok := c.entries[meta.Key]; ok {
+		c.removeElement(elem)
+	}
+	if err := os.Rename(tmpPath, c.dataPath(name)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.metaPath(name), raw, 0644); err != nil {
+		os.Remove(c.dataPath(name))
+		return err
+	}
+
+	m := meta
+	c.entries[meta.Key] = c.lru.PushFront(&entry{meta: &m, name: name})
+	if meta.RequestURL != "" {
+		c.aliases[meta.RequestURL] = meta.Key
+	}
+	c.curSize += meta.Size
+	c.evictIfNeeded()
+	logDebug("Disk cache stored %s (%d bytes)", meta.Key, meta.Size)
+	return nil
+}
+
+// evictIfNeeded 超出容量时淘汰最久未访问的项, 需持有锁
+func (c *Cache) evictIfNeeded() {
+	for c.maxSize > 0 && c.curSize > c.maxSize {
+		oldest := c.lru.Back()
+		if oldest == nil {
+			return
+		}
+		logDebug("Disk cache evict %s", oldest.Value.(*entry).meta.Key)
+		c.removeElement(oldest)
+	}
+}
+
+// removeElement 删除缓存项及其文件, 需持有锁
+func (c *Cache) removeElement(elem *list.Element) {
+	e := elem.Value.(*entry)
+	c.lru.Remove(elem)
+	delete(c.entries, e.meta.Key)
+	if c.aliases[e.meta.RequestURL] == e.meta.Key {
+		delete(c.aliases, e.meta.RequestURL)
+	}
+	c.curSize -= e.meta.Size
+	c.removeFiles(e.name)
+}
+
+func (c *Cache) removeFiles(name string) {
+	os.Remove(c.dataPath(name))
+	os.Remove(c.metaPath(name))
+}
+
+// Stats 返回缓存项数量与占用字节数
+func (c *Cache) Stats() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len(), c.curSize
+}
+
+func (c *Cache) cleanupLoop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.cleanupExpired()
+		case <-c.stopCleanup:
+			return
+		}
+	}
+}
+
+// cleanupExpired 清理过期项
+func (c *Cache) cleanupExpired() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for elem := c.lru.Back(); elem != nil; {
+		prev := elem.Prev()
+		if c.expired(elem.Value.(*entry).meta, now) {
+			c.removeElement(elem)
+		}
+		elem = prev
+	}
+}
+
+// StopCleanup 停止后台清理
+func (c *Cache) StopCleanup() {
+	close(c.stopCleanup)
+	c.wg.Wait()
+}
+
+// teeReader 在读取上游响应体的同时写入临时文件
+type teeReader struct {
+	cache        *Cache
+	body         io.ReadCloser
+	tmp          *os.File
+	meta         Meta
+	name         string
+	expectedSize int64
+	valid        func() bool
+	written      int64
+	failed       bool
+	done         bool
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	n, err := t.body.Read(p)
+	if n > 0 && !t.failed && !t.done {
+		if _, werr := t.tmp.Write(p[:n]); werr != nil {
+			logWarning("Disk cache write failed for %s: %v", t.meta.Key, werr)
+			t.abort()
+		} else {
+			t.written += int64(n)
+		}
+	}
+	if !t.failed && !t.done {
+		switch {
+		case err != nil && !errors.Is(err, io.EOF):
+			t.abort()
+		case errors.Is(err, io.EOF) || t.expectedSize >= 0 && t.written >= t.expectedSize:
+			// 已知长度时读满即提交, 上层可能以 LimitReader 读取而不再读到 EOF
+			t.finish()
+		}
+	}
+	return n, err
+}
+
+// finish 上游读取完毕或已读满预期大小, 校验大小后提交
+func (t *teeReader) finish() {
+	t.done = true
+	tmpPath := t.tmp.Name()
+	if err := t.tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return
+	}
+	if t.expectedSize >= 0 && t.written != t.expectedSize {
+		logWarning("Disk cache size mismatch for %s: got %d, want %d", t.meta.Key, t.written, t.expectedSize)
+		os.Remove(tmpPath)
+		return
+	}
+	if t.valid != nil && !t.valid() {
+		logWarning("Disk cache discarded invalid content for %s", t.meta.Key)
+		os.Remove(tmpPath)
+		return
+	}
+	t.meta.Size = t.written
+	if err := t.cache.commit(tmpPath, t.name, t.meta); err != nil {
+		logWarning("Disk cache commit failed for %s: %v", t.meta.Key, err)
+		os.Remove(tmpPath)
+	}
+}
+
+// abort 放弃本次缓存写入
+func (t *teeReader) abort() {
+	t.failed = true
+	tmpPath := t.tmp.Name()
+	t.tmp.Close()
+	os.Remove(tmpPath)
+}
+
+func (t *teeReader) Close() error {
+	if !t.done && !t.failed {
+		// 客户端中途断开, 不完整的数据不可缓存
+		t.abort()
+	}
+	return t.body.Close()
+}
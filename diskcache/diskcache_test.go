@@ -0,0 +1,106 @@
+package diskcache
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "diskcache-test")
+	if err != nil {
+		panic(err)
+	}
+	logger.Init(filepath.Join(dir, "test.log"), 1)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// noEOFBody 读完数据后不再返回 EOF, 模拟仅按 Content-Length 读取的上层
+type noEOFBody struct {
+	r *bytes.Reader
+}
+
+func (b *noEOFBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, errors.New("read past expected length")
+	}
+	return b.r.Read(p)
+}
+
+func (b *noEOFBody) Close() error { return nil }
+
+// errBody 返回数据的同时返回读取错误
+type errBody struct {
+	data []byte
+}
+
+func (b *errBody) Read(p []byte) (int, error) {
+	return copy(p, b.data), errors.New("upstream failed")
+}
+
+func (b *errBody) Close() error { return nil }
+
+func TestTeeCommit(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	tests := []struct {
+		name         string
+		body         func() io.ReadCloser
+		expectedSize int64
+		read         int // 读取的字节数, -1 表示读到出错或EOF
+		valid        func() bool
+		want         bool
+	}{
+		{"fixed length without EOF", func() io.ReadCloser { return &noEOFBody{r: bytes.NewReader(data)} }, int64(len(data)), len(data), nil, true},
+		{"unknown length until EOF", func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data)) }, -1, -1, nil, true},
+		{"fixed length with EOF", func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data)) }, int64(len(data)), -1, nil, true},
+		{"short body", func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data[:8])) }, int64(len(data)), -1, nil, false},
+		{"longer than expected", func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data)) }, 8, -1, nil, false},
+		{"closed before complete", func() io.ReadCloser { return &noEOFBody{r: bytes.NewReader(data)} }, int64(len(data)), 8, nil, false},
+		{"invalid content", func() io.ReadCloser { return &noEOFBody{r: bytes.NewReader(data)} }, int64(len(data)), len(data), func() bool { return false }, false},
+		{"read error with full data", func() io.ReadCloser { return &errBody{data: data} }, int64(len(data)) + 1, -1, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(t.TempDir(), 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.StopCleanup()
+
+			key := "https://example.com/" + tt.name
+			tee := c.Tee(tt.body(), Meta{Key: key}, tt.expectedSize, tt.valid)
+			if tt.read >= 0 {
+				buf := make([]byte, tt.read)
+				if _, err := io.ReadFull(tee, buf); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				io.Copy(io.Discard, tee)
+			}
+			// 在 Close 之前检查, 提交不应依赖 Close
+			f, meta, ok := c.Open(key)
+			if ok != tt.want {
+				t.Fatalf("Open(%s) hit = %v, want %v", key, ok, tt.want)
+			}
+			tee.Close()
+			if !ok {
+				if n, _ := c.Stats(); n != 0 {
+					t.Fatalf("Stats() entries = %d, want 0", n)
+				}
+				return
+			}
+			defer f.Close()
+			got, _ := io.ReadAll(f)
+			if !bytes.Equal(got, data) || meta.Size != int64(len(data)) {
+				t.Fatalf("cached %q (size %d), want %q", got, meta.Size, data)
+			}
+		})
+	}
+}
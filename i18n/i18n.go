@@ -0,0 +1,175 @@
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+//go:embed locales/*.toml
+var localesFS embed.FS
+
+// Message 错误码对应的可翻译文本
+type Message struct {
+	StatusText string `toml:"statusText"`
+	HelpInfo   string `toml:"helpInfo"`
+}
+
+// Catalog 按语言标签组织的错误消息
+type Catalog struct {
+	messages    map[string]map[string]Message // 语言 -> 错误码 -> 消息
+	primary     map[string]string             // 主语言子标签 -> 语言, 如 "zh" -> "zh-CN"
+	defaultLang string
+}
+
+// New 加载内置消息, dir 不为空时以其中的 <语言>.toml 覆盖或新增
+func New(defaultLang string, dir string) (*Catalog, error) {
+	c := &Catalog{messages: make(map[string]map[string]Message), primary: make(map[string]string)}
+	if err := c.loadFS(localesFS, "locales"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := c.loadFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+
+	langs := c.Languages()
+	for _, lang := range langs {
+		p := primaryTag(lang)
+		if _, ok := c.primary[p]; !ok {
+			c.primary[p] = lang
+		}
+	}
+	lang, ok := c.lookupLang(defaultLang)
+	if !ok {
+		return nil, fmt.Errorf("default language %s has no messages", defaultLang)
+	}
+	c.defaultLang = lang
+	return c, nil
+}
+
+func (c *Catalog) loadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.toml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var messages map[string]Message
+		if err := toml.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		lang := strings.TrimSuffix(path.Base(file), ".toml")
+		existing, ok := c.messages[lang]
+		if !ok {
+			existing = make(map[string]Message)
+			c.messages[lang] = existing
+		}
+		// 覆盖文件只需包含需要修改的字段
+		for code, msg := range messages {
+			merged := existing[code]
+			if msg.StatusText != "" {
+				merged.StatusText = msg.StatusText
+			}
+			if msg.HelpInfo != "" {
+				merged.HelpInfo = msg.HelpInfo
+			}
+			existing[code] = merged
+		}
+	}
+	return nil
+}
+
+// Languages 返回已加载的语言标签
+func (c *Catalog) Languages() []string {
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// DefaultLanguage 返回默认语言
+func (c *Catalog) DefaultLanguage() string {
+	return c.defaultLang
+}
+
+// lookupLang 以完整标签(不区分大小写)或主语言子标签匹配已加载的语言
+func (c *Catalog) lookupLang(tag string) (string, bool) {
+	for lang := range c.messages {
+		if strings.EqualFold(lang, tag) {
+			return lang, true
+		}
+	}
+	lang, ok := c.primary[primaryTag(tag)]
+	return lang, ok
+}
+
+// Match 按 Accept-Language 的权重选择语言, 均无法匹配时返回默认语言
+func (c *Catalog) Match(acceptLanguage string) string {
+	type candidate struct {
+		tag string
+		q   float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	for _, cand := range candidates {
+		if cand.tag == "*" {
+			return c.defaultLang
+		}
+		if lang, ok := c.lookupLang(cand.tag); ok {
+			return lang
+		}
+	}
+	return c.defaultLang
+}
+
+// Lookup 返回错误码在指定语言下的消息, 缺失的字段取默认语言
+func (c *Catalog) Lookup(lang string, code string) (Message, bool) {
+	msg, ok := c.messages[lang][code]
+	if lang != c.defaultLang && (msg.StatusText == "" || msg.HelpInfo == "") {
+		fallback, found := c.messages[c.defaultLang][code]
+		if msg.StatusText == "" {
+			msg.StatusText = fallback.StatusText
+		}
+		if msg.HelpInfo == "" {
+			msg.HelpInfo = fallback.HelpInfo
+		}
+		ok = ok || found
+	}
+	return msg, ok
+}
+
+// primaryTag 返回语言标签的主语言子标签, 如 "en-US" -> "en"
+func primaryTag(tag string) string {
+	p, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return strings.ToLower(p)
+}
@@ -0,0 +1,34 @@
+package i18n
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	c, err := New("zh-CN", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "zh-CN"},
+		{"en", "en"},
+		{"en-US,en;q=0.9", "en"},
+		{"EN-gb", "en"},
+		{"zh-CN", "zh-CN"},
+		{"zh-TW", "zh-CN"},
+		{"zh_HK", "zh-CN"},
+		{"fr, *;q=0.1", "zh-CN"},
+		{"fr, en;q=0.5", "en"},
+		{"zh;q=0.5, en;q=0.8", "en"},
+		{"en;q=0, zh", "zh-CN"},
+		{"de;q=0.9, en;q=invalid", "en"},
+		{"*, en;q=0.5", "zh-CN"},
+		{" , ;q=1, en", "en"},
+	}
+	for _, tt := range tests {
+		if got := c.Match(tt.accept); got != tt.want {
+			t.Errorf("Match(%q) = %s, want %s", tt.accept, got, tt.want)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"ghproxy/api"
 	"ghproxy/auth"
+	"ghproxy/ban"
 	"ghproxy/config"
 	"ghproxy/middleware/loggin"
 	"ghproxy/proxy"
@@ -172,8 +173,13 @@ func loadlist(cfg *config.Config) {
 	auth.Init(cfg)
 }
 
+func setupBan(cfg *config.Config) {
+	ban.Init(cfg)
+}
+
 func setupApi(cfg *config.Config, r *server.Hertz, version string) {
 	api.InitHandleRouter(cfg, r, version)
+	api.InitAdminRouter(cfg, r, cfgfile, limiter, iplimiter)
 }
 
 func setupRateLimit(cfg *config.Config) {
@@ -393,6 +399,7 @@ func init() {
 		setMemLimit(cfg)
 		loadlist(cfg)
 		setupRateLimit(cfg)
+		setupBan(cfg)
 		if cfg.Docker.Enabled {
 			wcache = proxy.InitWeakCache()
 		}
@@ -460,49 +467,7 @@ func main() {
 	setupApi(cfg, r, version)
 	setupPages(cfg, r)
 
-	r.GET("/github.com/:user/:repo/releases/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "releases")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/github.com/:user/:repo/archive/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "releases")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/github.com/:user/:repo/blob/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "blob")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/github.com/:user/:repo/raw/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "raw")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/github.com/:user/:repo/info/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "clone")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-	r.GET("/github.com/:user/:repo/git-upload-pack", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "clone")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/raw.githubusercontent.com/:user/:repo/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "raw")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/gist.githubusercontent.com/:user/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "gist")
-		proxy.NoRouteHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
-
-	r.GET("/api.github.com/repos/:user/:repo/*filepath", func(ctx context.Context, c *app.RequestContext) {
-		c.Set("matcher", "api")
-		proxy.RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
-	})
+	proxy.RegisterRoutes(r, cfg, limiter, iplimiter)
 
 	r.GET("/v2/", func(ctx context.Context, c *app.RequestContext) {
 		emptyJSON := "{}"
@@ -0,0 +1,324 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// 选择策略
+const (
+	StrategyRoundRobin = "round-robin"
+	StrategyLeastConn  = "least-conn"
+	StrategyFailover   = "failover"
+)
+
+// DialFunc 与 http.Transport.DialContext 相同签名的拨号函数
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// member 代理池中的一个代理
+type member struct {
+	url     *url.URL
+	addr    string // host:port, 用于关联拨号与活跃连接数
+	healthy atomic.Bool
+	fails   atomic.Int32 // 连续失败次数(健康检查与拨号失败)
+	conns   atomic.Int64 // 活跃连接数
+
+	lastCheck atomic.Pointer[time.Time]
+	lastError atomic.Pointer[string]
+}
+
+// MemberState 对外暴露的代理状态
+type MemberState struct {
+	URL       string    `json:"url"`
+	Healthy   bool      `json:"healthy"`
+	Fails     int32     `json:"fails"`
+	Conns     int64     `json:"conns"`
+	LastCheck time.Time `json:"lastCheck"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// Options 代理池配置
+type Options struct {
+	Proxies             []string
+	Strategy            string
+	HealthCheckURL      string
+	HealthCheckInterval time.Duration // 0 表示不进行主动健康检查
+	HealthCheckTimeout  time.Duration
+	MaxFails            int // 连续失败多少次后移出轮换
+}
+
+// Pool 出站代理池, 支持 http/https/socks5/socks5h
+type Pool struct {
+	members  []*member
+	byAddr   map[string]*member
+	strategy string
+	maxFails int32
+	next     atomic.Uint64
+
+	checkURL      string
+	checkInterval time.Duration
+	checkTimeout  time.Duration
+	dial          DialFunc // 健康检查使用的底层拨号器
+	stop          chan struct{}
+	stopOnce      sync.Once
+}
+
+// New 创建代理池, 无效或不支持的代理地址会被忽略
+func New(opts Options) (*Pool, error) {
+	p := &Pool{
+		byAddr:        make(map[string]*member),
+		strategy:      strings.ToLower(opts.Strategy),
+		maxFails:      int32(opts.MaxFails),
+		checkURL:      opts.HealthCheckURL,
+		checkInterval: opts.HealthCheckInterval,
+		checkTimeout:  opts.HealthCheckTimeout,
+		stop:          make(chan struct{}),
+	}
+	switch p.strategy {
+	case StrategyRoundRobin, StrategyLeastConn, StrategyFailover:
+	case "":
+		p.strategy = StrategyRoundRobin
+	default:
+		return nil, fmt.Errorf("unknown outbound strategy: %s", opts.Strategy)
+	}
+	if p.maxFails <= 0 {
+		p.maxFails = 3
+	}
+	if p.checkTimeout <= 0 {
+		p.checkTimeout = 5 * time.Second
+	}
+
+	for _, raw := range opts.Proxies {
+		u, err := ParseProxyURL(raw)
+		if err != nil {
+			logWarning("Skipping outbound proxy %q: %v", raw, err)
+			continue
+		}
+		m := &member{url: u, addr: u.Host}
+		m.healthy.Store(true)
+		p.members = append(p.members, m)
+		p.byAddr[m.addr] = m
+	}
+	if len(p.members) == 0 {
+		return nil, errors.New("no valid outbound proxy in pool")
+	}
+	return p, nil
+}
+
+// ParseProxyURL 解析代理地址并补全默认端口
+func ParseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, err
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	var defaultPort string
+	switch u.Scheme {
+	case "http":
+		defaultPort = "80"
+	case "https":
+		defaultPort = "443"
+	case "socks5", "socks5h":
+		defaultPort = "1080"
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("missing proxy host")
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	return u, nil
+}
+
+// Select 按策略选择一个健康的代理, 全部不健康时仍在全部代理中选择以免流量静默直连
+func (p *Pool) Select() *url.URL {
+	candidates := make([]*member, 0, len(p.members))
+	for _, m := range p.members {
+		if m.healthy.Load() {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.members
+	}
+
+	var chosen *member
+	switch p.strategy {
+	case StrategyFailover:
+		chosen = candidates[0]
+	case StrategyLeastConn:
+		chosen = candidates[0]
+		for _, m := range candidates[1:] {
+			if m.conns.Load() < chosen.conns.Load() {
+				chosen = m
+			}
+		}
+	default:
+		chosen = candidates[p.next.Add(1)%uint64(len(candidates))]
+	}
+	return chosen.url
+}
+
+// WrapDialer 统计到各代理的活跃连接数, 并将拨号失败计入代理的连续失败次数
+// 首次包装的拨号器同时用于健康检查, 需在 StartHealthCheck 之前调用
+func (p *Pool) WrapDialer(dial DialFunc) DialFunc {
+	if p.dial == nil {
+		p.dial = dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		m, ok := p.byAddr[addr]
+		if !ok {
+			return conn, err
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				p.markFailure(m, err)
+			}
+			return nil, err
+		}
+		m.conns.Add(1)
+		return &trackedConn{Conn: conn, m: m}, nil
+	}
+}
+
+// trackedConn 关闭时减少代理的活跃连接数
+type trackedConn struct {
+	net.Conn
+	m    *member
+	once sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.m.conns.Add(-1) })
+	return c.Conn.Close()
+}
+
+func (p *Pool) markFailure(m *member, err error) {
+	msg := err.Error()
+	m.lastError.Store(&msg)
+	if m.fails.Add(1) >= p.maxFails && m.healthy.CompareAndSwap(true, false) {
+		logWarning("Outbound proxy %s removed from rotation: %v", m.url.Redacted(), err)
+	}
+}
+
+func (p *Pool) markSuccess(m *member) {
+	m.fails.Store(0)
+	if m.healthy.CompareAndSwap(false, true) {
+		logInfo("Outbound proxy %s is healthy again", m.url.Redacted())
+	}
+}
+
+// StartHealthCheck 启动周期性健康检查
+func (p *Pool) StartHealthCheck() {
+	if p.checkInterval <= 0 || p.checkURL == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.checkInterval)
+		defer ticker.Stop()
+		p.checkAll()
+		for {
+			select {
+			case <-ticker.C:
+				p.checkAll()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止健康检查
+func (p *Pool) Stop() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, m := range p.members {
+		wg.Add(1)
+		go func(m *member) {
+			defer wg.Done()
+			p.check(m)
+		}(m)
+	}
+	wg.Wait()
+}
+
+// check 通过代理请求健康检查地址, 收到任意HTTP响应即视为代理可用
+func (p *Pool) check(m *member) {
+	tr := &http.Transport{
+		Proxy:             http.ProxyURL(m.url),
+		DialContext:       p.dial,
+		DisableKeepAlives: true,
+	}
+	defer tr.CloseIdleConnections()
+	ctx, cancel := context.WithTimeout(context.Background(), p.checkTimeout)
+	defer cancel()
+
+	now := time.Now()
+	m.lastCheck.Store(&now)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.checkURL, nil)
+	if err != nil {
+		p.markFailure(m, err)
+		return
+	}
+	resp, err := (&http.Client{Transport: tr}).Do(req)
+	if err != nil {
+		logDebug("Outbound proxy %s health check failed: %v", m.url.Redacted(), err)
+		p.markFailure(m, err)
+		return
+	}
+	resp.Body.Close()
+	p.markSuccess(m)
+}
+
+// States 返回代理池中各代理的状态
+func (p *Pool) States() []MemberState {
+	states := make([]MemberState, 0, len(p.members))
+	for _, m := range p.members {
+		s := MemberState{
+			URL:     m.url.Redacted(),
+			Healthy: m.healthy.Load(),
+			Fails:   m.fails.Load(),
+			Conns:   m.conns.Load(),
+		}
+		if t := m.lastCheck.Load(); t != nil {
+			s.LastCheck = *t
+		}
+		if e := m.lastError.Load(); e != nil {
+			s.LastError = *e
+		}
+		states = append(states, s)
+	}
+	return states
+}
+
+// Strategy 返回当前选择策略
+func (p *Pool) Strategy() string {
+	return p.strategy
+}
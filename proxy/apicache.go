@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"ghproxy/apicache"
+	"ghproxy/config"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var (
+	apiCache    *apicache.Cache
+	apiCacheTTL time.Duration
+)
+
+// InitAPICache 初始化 GitHub API 响应缓存
+func InitAPICache(cfg *config.Config) {
+	apiCache = nil
+	if !cfg.APICache.Enabled {
+		return
+	}
+	apiCacheTTL = time.Duration(cfg.APICache.TTL) * time.Second
+	apiCache = apicache.New(int64(cfg.APICache.MaxSize)*1024*1024, int64(cfg.APICache.MaxEntrySize)*1024)
+	logInfo("API cache enabled, ttl: %ds, maxSize: %dMB, maxEntrySize: %dKB", cfg.APICache.TTL, cfg.APICache.MaxSize, cfg.APICache.MaxEntrySize)
+}
+
+// APICacheStats 返回 API 缓存统计
+func APICacheStats() (bool, apicache.Stats) {
+	if apiCache == nil {
+		return false, apicache.Stats{}
+	}
+	return true, apiCache.Stats()
+}
+
+// apiCacheable 判断请求是否可使用 API 缓存, 仅缓存不带 Range 的 GET 请求
+func apiCacheable(c *app.RequestContext, matcher string) bool {
+	if apiCache == nil || matcher != "api" || string(c.Request.Method()) != http.MethodGet {
+		return false
+	}
+	return len(c.Request.Header.Peek("Range")) == 0
+}
+
+// apiCacheKey 以URL, 鉴权身份及影响响应内容的请求头作为缓存键
+// 鉴权身份取发往上游的 Authorization 的摘要, 不同令牌的响应互不共享
+func apiCacheKey(req *http.Request) string {
+	var identity string
+	if authorization := req.Header.Get("Authorization"); authorization != "" {
+		sum := sha256.Sum256([]byte(authorization))
+		identity = hex.EncodeToString(sum[:16])
+	}
+	return strings.Join([]string{req.URL.String(), identity, req.Header.Get("Accept"), req.Header.Get("Accept-Encoding")}, "\n")
+}
+
+// lookupAPICache 有效期内命中时直接响应; 已过期但带有校验值时改写请求为条件请求, 返回该条目用于处理 304
+func lookupAPICache(c *app.RequestContext, cfg *config.Config, req *http.Request, u string, matcher string, key string) (*apicache.Entry, bool) {
+	entry, ok := apiCache.Get(key)
+	if !ok {
+		apiCache.RecordMiss()
+		return nil, false
+	}
+	if entry.Fresh(apiCacheTTL) {
+		apiCache.RecordHit()
+		serveAPIEntry(c, cfg, u, matcher, entry, "HIT")
+		return entry, true
+	}
+	if !entry.Revalidatable() {
+		apiCache.Delete(key)
+		apiCache.RecordMiss()
+		return nil, false
+	}
+
+	// 客户端的条件请求头由本地按缓存条目处理
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+	return entry, false
+}
+
+// revalidateAPIEntry 以上游 304 响应更新缓存条目的响应头与有效期, X-RateLimit-* 等响应头取 304 中的最新值
+func revalidateAPIEntry(key string, entry *apicache.Entry, header http.Header) *apicache.Entry {
+	updated := &apicache.Entry{
+		Header:       entry.Header.Clone(),
+		Body:         entry.Body,
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+		StoredAt:     time.Now(),
+	}
+	for k, vs := range header {
+		if k == "Content-Length" {
+			continue
+		}
+		updated.Header[k] = vs
+	}
+	if etag := header.Get("ETag"); etag != "" {
+		updated.ETag = etag
+	}
+	apiCache.Put(key, updated)
+	apiCache.RecordRevalidated()
+	return updated
+}
+
+// serveAPIEntry 以缓存条目响应, 客户端的条件请求与条目匹配时返回 304
+func serveAPIEntry(c *app.RequestContext, cfg *config.Config, u string, matcher string, entry *apicache.Entry, status string) {
+	for key, values := range entry.Header {
+		if _, shouldRemove := respHeadersToRemove[key]; shouldRemove || key == "Content-Length" {
+			continue
+		}
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+	rewriteLinkHeader(c, cfg, entry.Header, matcher)
+	c.Header("X-GHProxy-Cache", status)
+	c.Header("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+	setCorsHeader(c, cfg)
+	applyResponseHeaderRules(c, u, matcher)
+
+	if inm := string(c.Request.Header.Peek("If-None-Match")); inm != "" {
+		if entry.ETag != "" && etagMatch(inm, entry.ETag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if ims := string(c.Request.Header.Peek("If-Modified-Since")); ims != "" && ims == entry.LastModified {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Status(http.StatusOK)
+	body := entry.Body
+	if apiRewritable(matcher, http.StatusOK, entry.Header) {
+		if rewritten, ok := rewriteAPIBytes(c, cfg, entry.Header, body); ok {
+			body = rewritten
+		}
+	}
+	c.Response.SetBody(body)
+	logDebug("%s %s %s API cache %s", c.ClientIP(), c.Method(), u, status)
+}
+
+// teeToAPICache 可缓存的上游响应在转发的同时写入 API 缓存
+func teeToAPICache(c *app.RequestContext, resp *http.Response, body io.ReadCloser, key string) io.ReadCloser {
+	if resp.StatusCode != http.StatusOK || resp.ContentLength > apiCache.MaxEntry() {
+		return body
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") || resp.Header.Get("Vary") == "*" {
+		return body
+	}
+	c.Header("X-GHProxy-Cache", "MISS")
+	return &apiCaptureBody{body: body, key: key, header: resp.Header.Clone(), expected: resp.ContentLength}
+}
+
+// apiCaptureBody 转发响应体的同时暂存内容, 完整读取后写入缓存, 超出单条上限或读取出错时放弃
+// 已知长度时读满即写入, 上层按 Content-Length 读取时不会再读到 EOF
+type apiCaptureBody struct {
+	body     io.ReadCloser
+	key      string
+	header   http.Header
+	expected int64 // 为 -1 表示长度未知, 以 EOF 为结束
+	buf      []byte
+	dropped  bool
+	stored   bool
+}
+
+func (b *apiCaptureBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.dropped || b.stored {
+		return n, err
+	}
+	if int64(len(b.buf)+n) > apiCache.MaxEntry() || err != nil && !errors.Is(err, io.EOF) {
+		b.dropped = true
+		b.buf = nil
+		return n, err
+	}
+	b.buf = append(b.buf, p[:n]...)
+
+	size := int64(len(b.buf))
+	switch {
+	case b.expected >= 0 && size == b.expected, b.expected < 0 && errors.Is(err, io.EOF):
+		b.stored = true
+		apiCache.Put(b.key, &apicache.Entry{
+			Header:       b.header,
+			Body:         b.buf,
+			ETag:         b.header.Get("ETag"),
+			LastModified: b.header.Get("Last-Modified"),
+			StoredAt:     time.Now(),
+		})
+	case errors.Is(err, io.EOF):
+		// 长度与 Content-Length 不一致, 不缓存不完整的响应
+		b.dropped = true
+		b.buf = nil
+	}
+	return n, err
+}
+
+func (b *apiCaptureBody) Close() error {
+	return b.body.Close()
+}
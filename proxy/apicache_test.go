@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"ghproxy/apicache"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// fixedBody 读完数据后不再返回 EOF, 模拟按 Content-Length 读取的上层
+type fixedBody struct {
+	r *bytes.Reader
+}
+
+func (b *fixedBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, errors.New("read past content length")
+	}
+	return b.r.Read(p)
+}
+
+func (b *fixedBody) Close() error { return nil }
+
+func TestAPICaptureBody(t *testing.T) {
+	data := []byte(`{"login":"octocat"}`)
+	tests := []struct {
+		name     string
+		body     io.ReadCloser
+		expected int64
+		read     int // 读取的字节数, -1 表示读到 EOF
+		want     bool
+	}{
+		{"fixed length without EOF", &fixedBody{r: bytes.NewReader(data)}, int64(len(data)), len(data), true},
+		{"unknown length until EOF", io.NopCloser(bytes.NewReader(data)), -1, -1, true},
+		{"partial read", &fixedBody{r: bytes.NewReader(data)}, int64(len(data)), 5, false},
+		{"shorter than content length", io.NopCloser(bytes.NewReader(data[:5])), int64(len(data)), -1, false},
+		{"over entry limit", io.NopCloser(bytes.NewReader(bytes.Repeat(data, 100))), -1, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiCache = apicache.New(1024*1024, 1024)
+			defer func() { apiCache = nil }()
+
+			b := &apiCaptureBody{body: tt.body, key: tt.name, header: http.Header{}, expected: tt.expected}
+			if tt.read >= 0 {
+				if _, err := io.ReadFull(b, make([]byte, tt.read)); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				io.Copy(io.Discard, b)
+			}
+			entry, ok := apiCache.Get(tt.name)
+			if ok != tt.want {
+				t.Fatalf("stored = %v, want %v", ok, tt.want)
+			}
+			if ok && !bytes.Equal(entry.Body, data) {
+				t.Fatalf("stored body %q, want %q", entry.Body, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"ghproxy/auth"
+	"ghproxy/config"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// apiMethodCheck 按 [apiPolicy] 检查 api 请求的方法, 需要 scope 的方法要求请求携带被授予该 scope 的令牌
+func apiMethodCheck(c *app.RequestContext, cfg *config.Config, matcher string, rawPath string) bool {
+	if matcher != "api" {
+		return false
+	}
+	method := string(c.Method())
+	if method == http.MethodPost && cfg.APIPolicy.GraphQLReadQueries && isGraphQLPath(rawPath) && graphqlReadOnly(c.Request.Body()) {
+		method = http.MethodGet
+	}
+
+	scope, ok := cfg.APIPolicy.Methods[method]
+	if !ok || scope == "-" {
+		ErrorPage(c, NewErrorWithStatusLookup(405, fmt.Sprintf("Method %s is not allowed for Github API", c.Method())))
+		logInfo("%s %s %s %s %s API-Policy: method not allowed", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol())
+		return true
+	}
+	if scope != "" && !auth.HasScope(c, cfg, scope) {
+		ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("Method %s requires a token with scope %s", c.Method(), scope)).WithCode("INSUFFICIENT_SCOPE"))
+		logInfo("%s %s %s %s %s API-Policy: missing scope %s", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol(), scope)
+		return true
+	}
+	return false
+}
+
+// isGraphQLPath 判断是否为 api.github.com/graphql
+func isGraphQLPath(rawPath string) bool {
+	kind, segs := lookupUpstream(rawPath)
+	return kind != nil && kind.name == "api" && len(segs) == 1 && segs[0] == "graphql"
+}
+
+// graphqlReadOnly 判断 GraphQL 请求体是否仅包含查询操作, 支持批量请求
+func graphqlReadOnly(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	reqs := []json.RawMessage{body}
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			return false
+		}
+	}
+	if len(reqs) == 0 {
+		return false
+	}
+	for _, raw := range reqs {
+		query, ok := graphqlQuery(raw)
+		if !ok || strings.TrimSpace(query) == "" || graphqlHasWrite(query) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphqlQuery 严格解析单个 GraphQL 请求对象中的 query 字段
+// encoding/json 对键名大小写不敏感且重复键以最后一个为准, 与上游的解析结果可能不同,
+// 因此存在重复键或大小写不同的 query 键时视为无法判定
+func graphqlQuery(raw json.RawMessage) (string, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return "", false
+	}
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", false
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return "", false
+		}
+		if _, dup := fields[key]; dup || key != "query" && strings.EqualFold(key, "query") {
+			return "", false
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return "", false
+		}
+		fields[key] = value
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return "", false
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", false
+	}
+	var query string
+	if err := json.Unmarshal(fields["query"], &query); err != nil {
+		return "", false
+	}
+	return query, true
+}
+
+// graphqlHasWrite 判断 GraphQL 文档中是否存在 mutation 或 subscription 操作
+// 仅检查顶层的操作类型关键字, 跳过注释, 字符串与选择集
+func graphqlHasWrite(doc string) bool {
+	depth := 0
+	for i := 0; i < len(doc); {
+		ch := doc[i]
+		switch {
+		case ch == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(doc[i:], `"""`):
+			end := strings.Index(doc[i+3:], `"""`)
+			if end < 0 {
+				return true
+			}
+			i += end + 6
+		case ch == '"':
+			i++
+			for i < len(doc) && doc[i] != '"' {
+				if doc[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case ch == '{' || ch == '(' || ch == '[':
+			depth++
+			i++
+		case ch == '}' || ch == ')' || ch == ']':
+			depth--
+			i++
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+			start := i
+			for i < len(doc) && (doc[i] == '_' || doc[i] >= 'a' && doc[i] <= 'z' || doc[i] >= 'A' && doc[i] <= 'Z' || doc[i] >= '0' && doc[i] <= '9') {
+				i++
+			}
+			if word := doc[start:i]; depth == 0 && (word == "mutation" || word == "subscription") {
+				return true
+			}
+		default:
+			i++
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import "testing"
+
+func TestGraphqlHasWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want bool
+	}{
+		{"shorthand query", `{ viewer { login } }`, false},
+		{"named query", `query Q { viewer { login } }`, false},
+		{"mutation", `mutation { addStar(input: {starrableId: "x"}) { clientMutationId } }`, true},
+		{"subscription", `subscription S { x }`, true},
+		{"mutation after query", `query A { a } mutation B { b }`, true},
+		{"field named mutation", `{ mutation { id } }`, false},
+		{"keyword in comment", "# mutation\n{ viewer { login } }", false},
+		{"keyword in string", `{ search(query: "mutation") { n } }`, false},
+		{"keyword in block string", `{ search(query: """mutation""") { n } }`, false},
+		{"escaped quote in string", `{ search(query: "\"mutation") { n } } mutation { x }`, true},
+		{"unterminated block string", `{ a(q: """mutation) }`, true},
+		{"mutation prefix word", `mutationX { a }`, false},
+	}
+	for _, tt := range tests {
+		if got := graphqlHasWrite(tt.doc); got != tt.want {
+			t.Errorf("%s: graphqlHasWrite(%q) = %v, want %v", tt.name, tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestGraphqlReadOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"query", `{"query":"{viewer{login}}"}`, true},
+		{"query with variables", `{"query":"query($n:Int){a(n:$n)}","variables":{"n":1},"operationName":null}`, true},
+		{"mutation", `{"query":"mutation{addStar(input:{}){clientMutationId}}"}`, false},
+		{"batch read", `[{"query":"{a}"},{"query":"{b}"}]`, true},
+		{"batch with mutation", `[{"query":"{a}"},{"query":"mutation{b}"}]`, false},
+		{"empty batch", `[]`, false},
+		{"empty query", `{"query":"  "}`, false},
+		{"null query", `{"query":null}`, false},
+		{"missing query", `{"variables":{}}`, false},
+		{"non-string query", `{"query":1}`, false},
+		{"not an object", `"{a}"`, false},
+		{"invalid json", `{"query":"{a}"`, false},
+		{"trailing data", `{"query":"{a}"} {"query":"mutation{b}"}`, false},
+		{"case variant key after", `{"query":"mutation{b}","QUERY":"{viewer{login}}"}`, false},
+		{"case variant key only", `{"Query":"{a}"}`, false},
+		{"duplicate key", `{"query":"mutation{b}","query":"{a}"}`, false},
+		{"escaped duplicate key", `{"query":"mutation{b}","\u0071uery":"{a}"}`, false},
+		{"duplicate other key", `{"query":"{a}","variables":{},"variables":{}}`, false},
+		{"duplicate key in batch", `[{"query":"{a}"},{"query":"mutation{b}","query":"{a}"}]`, false},
+		{"nested duplicate ignored", `{"query":"{a}","variables":{"x":1,"x":2}}`, true},
+		{"surrounding whitespace", " \n{\"query\":\"{a}\"}\n", true},
+	}
+	for _, tt := range tests {
+		if got := graphqlReadOnly([]byte(tt.body)); got != tt.want {
+			t.Errorf("%s: graphqlReadOnly(%s) = %v, want %v", tt.name, tt.body, got, tt.want)
+		}
+	}
+}
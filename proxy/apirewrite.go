@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"ghproxy/config"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var (
+	apiRewriteEnabled bool
+	apiRewriteFields  map[string]struct{} // 为 nil 表示改写所有字段
+)
+
+var linkURLPattern = regexp.MustCompile(`<[^>]*>`)
+
+// InitAPIRewrite 初始化 API 响应中链接的改写
+func InitAPIRewrite(cfg *config.Config) {
+	apiRewriteEnabled = cfg.APIRewrite.Enabled
+	apiRewriteFields = nil
+	if !apiRewriteEnabled {
+		return
+	}
+	if len(cfg.APIRewrite.Fields) > 0 {
+		apiRewriteFields = make(map[string]struct{}, len(cfg.APIRewrite.Fields))
+		for _, field := range cfg.APIRewrite.Fields {
+			apiRewriteFields[field] = struct{}{}
+		}
+	}
+	logInfo("API rewrite enabled, link: %t, fields: %v, maxSize: %dKB", cfg.APIRewrite.Link, cfg.APIRewrite.Fields, cfg.APIRewrite.MaxSize)
+}
+
+// proxiedURL 将本代理可处理的上游链接改写为经由本代理的地址
+func proxiedURL(host string, target string, cfg *config.Config) (string, bool) {
+	if !strings.HasPrefix(target, "https://") {
+		return "", false
+	}
+	if _, _, _, err := Matcher(target, cfg); err != nil {
+		return "", false
+	}
+	return "https://" + host + "/" + target, true
+}
+
+// rewriteLinkHeader 改写分页 Link 响应头中的链接
+func rewriteLinkHeader(c *app.RequestContext, cfg *config.Config, header http.Header, matcher string) {
+	if !apiRewriteEnabled || !cfg.APIRewrite.Link || matcher != "api" {
+		return
+	}
+	link := header.Get("Link")
+	if link == "" {
+		return
+	}
+	host := string(c.Request.Host())
+	rewritten := linkURLPattern.ReplaceAllStringFunc(link, func(m string) string {
+		if proxied, ok := proxiedURL(host, m[1:len(m)-1], cfg); ok {
+			return "<" + proxied + ">"
+		}
+		return m
+	})
+	c.Header("Link", rewritten)
+}
+
+// apiRewritable 判断 API 响应体是否需要改写, 仅处理未压缩或 gzip 压缩的 JSON
+func apiRewritable(matcher string, status int, header http.Header) bool {
+	if !apiRewriteEnabled || matcher != "api" || status < 200 || status >= 300 {
+		return false
+	}
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "gzip" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// rewriteAPIBody 读取完整响应体并改写其中的链接, 超出 maxSize 时原样转发
+// 返回的长度为 -1 表示长度未知
+func rewriteAPIBody(c *app.RequestContext, cfg *config.Config, header http.Header, body io.ReadCloser, bodySize int) (io.ReadCloser, int) {
+	maxSize := int64(cfg.APIRewrite.MaxSize) * 1024
+	data, err := io.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil || int64(len(data)) > maxSize {
+		return &prefixedBody{Reader: io.MultiReader(bytes.NewReader(data), body), Closer: body}, bodySize
+	}
+	body.Close()
+	rewritten, ok := rewriteAPIBytes(c, cfg, header, data)
+	if !ok {
+		return io.NopCloser(bytes.NewReader(data)), len(data)
+	}
+	return io.NopCloser(bytes.NewReader(rewritten)), len(rewritten)
+}
+
+// rewriteAPIBytes 改写 JSON 响应体中的链接, gzip 响应解压后以未压缩形式返回
+// 无需改写或无法处理时返回 false, 调用方应使用原始内容
+func rewriteAPIBytes(c *app.RequestContext, cfg *config.Config, header http.Header, data []byte) ([]byte, bool) {
+	plain := data
+	if header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, false
+		}
+		maxSize := int64(cfg.APIRewrite.MaxSize) * 1024
+		plain, err = io.ReadAll(io.LimitReader(zr, maxSize+1))
+		zr.Close()
+		if err != nil || int64(len(plain)) > maxSize {
+			return nil, false
+		}
+	}
+
+	host := string(c.Request.Host())
+	rewritten, changed := rewriteJSONStrings(plain, func(key string, value string) (string, bool) {
+		if apiRewriteFields != nil {
+			if _, ok := apiRewriteFields[key]; !ok {
+				return "", false
+			}
+		}
+		return proxiedURL(host, value, cfg)
+	})
+	if !changed {
+		return nil, false
+	}
+	c.Response.Header.Del("Content-Encoding")
+	return rewritten, true
+}
+
+// rewriteJSONStrings 逐个扫描 JSON 中的字符串值, 保留原有格式
+// key 为该值所属的最近一个对象键, 数组元素沿用数组所属的键
+func rewriteJSONStrings(data []byte, rewrite func(key string, value string) (string, bool)) ([]byte, bool) {
+	var (
+		out     bytes.Buffer
+		lastKey string
+		last    int
+		changed bool
+	)
+	for i := 0; i < len(data); {
+		if data[i] != '"' {
+			i++
+			continue
+		}
+		end := scanJSONString(data, i)
+		if end < 0 {
+			break
+		}
+		j := end
+		for j < len(data) && (data[j] == ' ' || data[j] == '\t' || data[j] == '\n' || data[j] == '\r') {
+			j++
+		}
+		isKey := j < len(data) && data[j] == ':'
+		if isKey || bytes.HasPrefix(data[i+1:end], []byte("https:")) {
+			var s string
+			if err := json.Unmarshal(data[i:end], &s); err == nil {
+				if isKey {
+					lastKey = s
+				} else if replaced, ok := rewrite(lastKey, s); ok {
+					out.Write(data[last:i])
+					out.Write(quoteJSONString(replaced))
+					last = end
+					changed = true
+				}
+			}
+		}
+		i = end
+	}
+	if !changed {
+		return data, false
+	}
+	out.Write(data[last:])
+	return out.Bytes(), true
+}
+
+// scanJSONString 返回从 start 处开始的字符串字面量结束后的位置, 未闭合时返回 -1
+func scanJSONString(data []byte, start int) int {
+	for i := start + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// quoteJSONString 编码 JSON 字符串, 不转义 HTML 字符
+func quoteJSONString(s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// prefixedBody 先输出已读取的部分, 再继续读取原响应体
+type prefixedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import "testing"
+
+func TestRewriteJSONStrings(t *testing.T) {
+	// 将值改写为 "<key>|<value>", 键为 skip 时不改写
+	rewrite := func(key string, value string) (string, bool) {
+		if key == "skip" {
+			return "", false
+		}
+		return key + "|" + value, true
+	}
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		changed bool
+	}{
+		{"single value", `{"url":"https://github.com/a"}`, `{"url":"url|https://github.com/a"}`, true},
+		{"keeps formatting", "{\n  \"url\" : \"https://x\",\n  \"n\": 1\n}", "{\n  \"url\" : \"url|https://x\",\n  \"n\": 1\n}", true},
+		{"non https values untouched", `{"a":"http://x","b":"text","c":1}`, `{"a":"http://x","b":"text","c":1}`, false},
+		{"rewrite declined", `{"skip":"https://x"}`, `{"skip":"https://x"}`, false},
+		{"key looks like url", `{"https://k":"v"}`, `{"https://k":"v"}`, false},
+		{"array uses owning key", `{"urls":["https://a","https://b"],"x":"https://c"}`, `{"urls":["urls|https://a","urls|https://b"],"x":"x|https://c"}`, true},
+		{"nested objects", `{"assets":[{"browser_download_url":"https://a"}],"html_url":"https://b"}`, `{"assets":[{"browser_download_url":"browser_download_url|https://a"}],"html_url":"html_url|https://b"}`, true},
+		{"escaped slashes decoded", `{"u":"https:\/\/github.com\/a"}`, `{"u":"u|https://github.com/a"}`, true},
+		{"html not escaped", `{"u":"https://x/?a=1&b=<2>"}`, `{"u":"u|https://x/?a=1&b=<2>"}`, true},
+		{"escaped quote before url", `{"a":"x\"https:","b":"https://y"}`, `{"a":"x\"https:","b":"b|https://y"}`, true},
+		{"escaped quote in value", `{"u":"https://x/\"q\""}`, `{"u":"u|https://x/\"q\""}`, true},
+		{"unterminated string", `{"u":"https://a","v":"https://b`, `{"u":"u|https://a","v":"https://b`, true},
+		{"top level array", `["https://a"]`, `["|https://a"]`, true},
+	}
+	for _, tt := range tests {
+		got, changed := rewriteJSONStrings([]byte(tt.data), rewrite)
+		if string(got) != tt.want || changed != tt.changed {
+			t.Errorf("%s: rewriteJSONStrings(%s) = %s, %v; want %s, %v", tt.name, tt.data, got, changed, tt.want, tt.changed)
+		}
+	}
+}
@@ -1,17 +1,33 @@
 package proxy
 
 import (
+	"context"
 	"errors"
 	"ghproxy/config"
+	"io"
+	"sync/atomic"
 
 	"github.com/WJQSERVER-STUDIO/go-utils/limitreader"
 	"golang.org/x/time/rate"
 )
 
-var (
-	bandwidthLimit rate.Limit
-	bandwidthBurst rate.Limit
-)
+// bandwidthSettings 单连接带宽限制, 管理接口修改时整体替换, 请求处理中不读取 cfg
+type bandwidthSettings struct {
+	enabled bool
+	limit   rate.Limit
+	burst   rate.Limit
+}
+
+var bandwidth atomic.Pointer[bandwidthSettings]
+
+// limitBandwidth 按当前的单连接带宽限制包装响应体, 已建立的连接沿用创建时的限制
+func limitBandwidth(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	bw := bandwidth.Load()
+	if bw == nil || !bw.enabled {
+		return body
+	}
+	return limitreader.NewRateLimitedReader(body, bw.limit, int(bw.burst), ctx)
+}
 
 func UnDefiendRateStringErrHandle(err error) error {
 	if errors.Is(err, &limitreader.UnDefiendRateStringErr{}) {
@@ -36,13 +52,14 @@ func SetGlobalRateLimit(cfg *config.Config) error {
 			logError("Failed to parse total bandwidth burst: %v", err)
 			return err
 		}
-		limitreader.SetGlobalRateLimit(totalLimit, int(totalBurst))
 		err = SetBandwidthLimit(cfg)
 		if UnDefiendRateStringErrHandle(err) != nil {
 			logError("Failed to set bandwidth limit: %v", err)
 			return err
 		}
+		limitreader.SetGlobalRateLimit(totalLimit, int(totalBurst))
 	} else {
+		bandwidth.Store(&bandwidthSettings{})
 		limitreader.SetGlobalRateLimit(rate.Inf, 0)
 	}
 	return nil
@@ -50,15 +67,17 @@ func SetGlobalRateLimit(cfg *config.Config) error {
 
 func SetBandwidthLimit(cfg *config.Config) error {
 	var err error
-	bandwidthLimit, err = limitreader.ParseRate(cfg.RateLimit.BandwidthLimit.SingleLimit)
+	bw := &bandwidthSettings{enabled: cfg.RateLimit.BandwidthLimit.Enabled}
+	bw.limit, err = limitreader.ParseRate(cfg.RateLimit.BandwidthLimit.SingleLimit)
 	if UnDefiendRateStringErrHandle(err) != nil {
 		logError("Failed to parse bandwidth limit: %v", err)
 		return err
 	}
-	bandwidthBurst, err = limitreader.ParseRate(cfg.RateLimit.BandwidthLimit.SingleBurst)
+	bw.burst, err = limitreader.ParseRate(cfg.RateLimit.BandwidthLimit.SingleBurst)
 	if UnDefiendRateStringErrHandle(err) != nil {
 		logError("Failed to parse bandwidth burst: %v", err)
 		return err
 	}
+	bandwidth.Store(bw)
 	return nil
 }
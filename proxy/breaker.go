@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ghproxy/breaker"
+	"ghproxy/config"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var upstreamBreaker *breaker.Breaker
+
+// InitBreaker 初始化上游熔断器, client/gitclient/ghcrclient 共用同一组按主机划分的状态
+func InitBreaker(cfg *config.Config) {
+	if !cfg.Httpc.Breaker.Enabled {
+		return
+	}
+	upstreamBreaker = breaker.New(
+		cfg.Httpc.Breaker.FailureThreshold,
+		time.Duration(cfg.Httpc.Breaker.OpenTimeout)*time.Second,
+		cfg.Httpc.Breaker.HalfOpenProbes,
+	)
+	logInfo("Upstream circuit breaker enabled, failureThreshold: %d, openTimeout: %ds", cfg.Httpc.Breaker.FailureThreshold, cfg.Httpc.Breaker.OpenTimeout)
+}
+
+// BreakerStates 返回各上游主机的熔断状态
+func BreakerStates() (bool, []breaker.HostState) {
+	if upstreamBreaker == nil {
+		return false, []breaker.HostState{}
+	}
+	return true, upstreamBreaker.States()
+}
+
+// breakerDo 经过熔断器发送请求, 熔断打开时直接返回 breaker.ErrOpen
+func breakerDo(cl *httpc.Client, req *http.Request) (*http.Response, error) {
+	if upstreamBreaker == nil {
+		return clientDo(cl, req)
+	}
+	host := req.URL.Host
+	token, err := upstreamBreaker.Allow(host)
+	if err != nil {
+		return nil, &circuitOpenError{host: host, retryAfter: upstreamBreaker.RetryAfter(host)}
+	}
+	resp, err := clientDo(cl, req)
+	upstreamBreaker.Report(host, token, breakerResult(resp, err), err)
+	return resp, err
+}
+
+// circuitOpenError 熔断打开时返回的错误, 携带建议的重试等待时长
+type circuitOpenError struct {
+	host       string
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("%s: %v", e.host, breaker.ErrOpen)
+}
+
+func (e *circuitOpenError) Unwrap() error {
+	return breaker.ErrOpen
+}
+
+// breakerResult 判定请求结果, 仅网络错误与网关类错误计为上游故障
+func breakerResult(resp *http.Response, err error) breaker.Result {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return breaker.ResultIgnore
+		}
+		return breaker.ResultFailure
+	}
+	if _, ok := retryStatuses[resp.StatusCode]; ok {
+		return breaker.ResultFailure
+	}
+	return breaker.ResultSuccess
+}
+
+// HandleUpstreamError 处理上游请求错误, 熔断打开时返回 503
+func HandleUpstreamError(c *app.RequestContext, u string, err error) {
+	var openErr *circuitOpenError
+	if !errors.As(err, &openErr) {
+		HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+		return
+	}
+	if openErr.retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(openErr.retryAfter.Seconds()))))
+	}
+	logWarning("%s %s %s %s %s Circuit-Open: %v", c.ClientIP(), c.Method(), u, c.UserAgent(), c.Request.Header.GetProtocol(), err)
+	ErrorPage(c, NewErrorWithStatusLookup(http.StatusServiceUnavailable, fmt.Sprintf("Upstream unavailable: %v", err)))
+}
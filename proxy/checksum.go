@@ -0,0 +1,237 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"ghproxy/checksum"
+	"ghproxy/config"
+	"ghproxy/weakcache"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// 校验模式
+const (
+	checksumVerifyOff     = "off"     // 仅计算摘要
+	checksumVerifyLog     = "log"     // 不一致时记录日志与统计
+	checksumVerifyEnforce = "enforce" // 不一致时不交付最后一个字节
+)
+
+const (
+	maxChecksumFileSize   = 1 << 20 // 校验文件大小上限
+	maxChecksumMismatches = 20      // 保留的最近不一致记录数
+)
+
+// ChecksumMismatch 校验不一致记录
+type ChecksumMismatch struct {
+	URL      string    `json:"url"`
+	Expected string    `json:"expected"`
+	Actual   string    `json:"actual"`
+	Source   string    `json:"source"`
+	Time     time.Time `json:"time"`
+}
+
+var (
+	checksumVerified   atomic.Int64
+	checksumMismatched atomic.Int64
+	checksumUnverified atomic.Int64
+
+	checksumMu         sync.Mutex
+	checksumMismatches []ChecksumMismatch
+
+	checksumFileCache *weakcache.Cache[string] // 校验文件URL -> 内容, 空字符串表示不存在
+)
+
+var releaseDownloadPattern = regexp.MustCompile(`^(https://github\.com/[^/]+/[^/]+/releases/(?:download/[^/]+|latest/download)/)([^/?#]+)`)
+
+// InitChecksum 初始化 release 资源的摘要计算与校验
+func InitChecksum(cfg *config.Config) error {
+	if !cfg.Checksum.Enabled {
+		return nil
+	}
+	switch cfg.Checksum.Verify {
+	case checksumVerifyOff, checksumVerifyLog, checksumVerifyEnforce:
+	case "":
+		cfg.Checksum.Verify = checksumVerifyOff
+	default:
+		return fmt.Errorf("unknown checksum verify mode: %s", cfg.Checksum.Verify)
+	}
+	if cfg.Checksum.Verify != checksumVerifyOff {
+		checksumFileCache = weakcache.NewCache[string](weakcache.DefaultExpiration, 256)
+	}
+	logInfo("Release checksum enabled, trailer: %v, verify: %s", cfg.Checksum.Trailer, cfg.Checksum.Verify)
+	return nil
+}
+
+// ChecksumStats 返回校验统计与最近的不一致记录
+func ChecksumStats() (int64, int64, int64, []ChecksumMismatch) {
+	checksumMu.Lock()
+	recent := make([]ChecksumMismatch, len(checksumMismatches))
+	copy(recent, checksumMismatches)
+	checksumMu.Unlock()
+	return checksumVerified.Load(), checksumMismatched.Load(), checksumUnverified.Load(), recent
+}
+
+// takeDigestQuery 取出并移除 ?sha256= 参数, 避免其改变上游(如CDN签名)地址
+func takeDigestQuery(u string) (string, string) {
+	base, rawQuery, ok := strings.Cut(u, "?")
+	if !ok {
+		return u, ""
+	}
+	var (
+		expected string
+		kept     []string
+	)
+	for _, param := range strings.Split(rawQuery, "&") {
+		if value, found := strings.CutPrefix(param, "sha256="); found {
+			expected = strings.ToLower(value)
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if len(kept) == 0 {
+		return base, expected
+	}
+	return base + "?" + strings.Join(kept, "&"), expected
+}
+
+// wrapChecksum 为 release 资源的完整响应附加摘要计算与校验
+// 启用 trailer 时摘要通过 trailer 返回, 调用方需改用 chunked 传输
+func wrapChecksum(c *app.RequestContext, cl *httpc.Client, cfg *config.Config, resp *http.Response, body io.ReadCloser, u string, expected string) (io.ReadCloser, bool) {
+	if resp.StatusCode != http.StatusOK || string(c.Request.Method()) != http.MethodGet {
+		return body, false
+	}
+
+	verify := cfg.Checksum.Verify != checksumVerifyOff
+	source := "query"
+	if !verify {
+		expected = ""
+	} else if expected != "" && !checksum.IsHex(expected) {
+		logWarning("%s %s %s Invalid sha256 query: %s", c.ClientIP(), c.Method(), u, expected)
+		expected = ""
+	}
+	if verify && expected == "" {
+		expected, source = lookupReleaseChecksum(c, cl, cfg, u)
+	}
+	if expected != "" {
+		c.Header("X-GHProxy-Sha256-Expected", expected)
+	}
+
+	trailer := cfg.Checksum.Trailer
+	if trailer {
+		// 提前声明 trailer, 值在响应体读取结束后写入
+		c.Response.Header.Trailer().Set("X-GHProxy-Sha256", "")
+		if verify {
+			c.Response.Header.Trailer().Set("X-GHProxy-Checksum", "")
+		}
+	}
+
+	enforce := cfg.Checksum.Verify == checksumVerifyEnforce
+	return checksum.NewReader(body, expected, resp.ContentLength, enforce, func(r *checksum.Reader) {
+		if trailer {
+			c.Response.Header.Trailer().Set("X-GHProxy-Sha256", r.Sum())
+			if verify {
+				c.Response.Header.Trailer().Set("X-GHProxy-Checksum", string(r.Result()))
+			}
+		}
+		recordChecksum(u, source, r)
+	}), trailer
+}
+
+func recordChecksum(u string, source string, r *checksum.Reader) {
+	switch r.Result() {
+	case checksum.ResultVerified:
+		checksumVerified.Add(1)
+		logDebug("Checksum verified %s sha256: %s", u, r.Sum())
+	case checksum.ResultMismatch:
+		checksumMismatched.Add(1)
+		logError("Checksum mismatch %s expected: %s actual: %s source: %s", u, r.Expected(), r.Sum(), source)
+		checksumMu.Lock()
+		checksumMismatches = append(checksumMismatches, ChecksumMismatch{
+			URL:      u,
+			Expected: r.Expected(),
+			Actual:   r.Sum(),
+			Source:   source,
+			Time:     time.Now(),
+		})
+		if len(checksumMismatches) > maxChecksumMismatches {
+			checksumMismatches = checksumMismatches[len(checksumMismatches)-maxChecksumMismatches:]
+		}
+		checksumMu.Unlock()
+	default:
+		checksumUnverified.Add(1)
+		logDebug("Checksum computed %s sha256: %s", u, r.Sum())
+	}
+}
+
+// lookupReleaseChecksum 在同一 release 中按配置顺序查找校验文件, 返回摘要与校验文件地址
+func lookupReleaseChecksum(c *app.RequestContext, cl *httpc.Client, cfg *config.Config, u string) (string, string) {
+	m := releaseDownloadPattern.FindStringSubmatch(u)
+	if m == nil {
+		return "", ""
+	}
+	prefix, file := m[1], m[2]
+	for _, candidate := range cfg.Checksum.ChecksumFiles {
+		name := strings.ReplaceAll(candidate, "{file}", file)
+		if name == file {
+			continue
+		}
+		fileURL := prefix + name
+		content, ok := fetchChecksumFile(c, cl, cfg, fileURL)
+		if !ok {
+			continue
+		}
+		if sum, found := checksum.ParseSums([]byte(content), file); found {
+			return sum, fileURL
+		}
+	}
+	return "", ""
+}
+
+// fetchChecksumFile 获取校验文件, 结果(包括不存在)会被缓存
+// 使用独立的上下文, 不受客户端重定向策略影响
+func fetchChecksumFile(c *app.RequestContext, cl *httpc.Client, cfg *config.Config, fileURL string) (string, bool) {
+	if content, ok := checksumFileCache.Get(fileURL); ok {
+		return content, content != ""
+	}
+
+	ctx, cancel := context.WithTimeout(withOutboundRoute(context.Background(), "releases"), 15*time.Second)
+	defer cancel()
+	req, err := cl.NewRequestBuilder(http.MethodGet, fileURL).NoDefaultHeaders().WithContext(ctx).Build()
+	if err != nil {
+		return "", false
+	}
+	AuthPassThrough(c, cfg, req)
+	poolToken := injectPoolToken(req, "releases")
+	resp, err := upstreamDo(cl, req, cfg)
+	if err != nil {
+		logWarning("Failed to fetch checksum file %s: %v", fileURL, err)
+		return "", false
+	}
+	reportPoolToken(poolToken, resp)
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		checksumFileCache.Put(fileURL, "")
+		return "", false
+	default:
+		return "", false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumFileSize))
+	if err != nil {
+		logWarning("Failed to read checksum file %s: %v", fileURL, err)
+		return "", false
+	}
+	checksumFileCache.Put(fileURL, string(data))
+	return string(data), len(data) > 0
+}
@@ -3,12 +3,13 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"ghproxy/apicache"
+	"ghproxy/checksum"
 	"ghproxy/config"
 	"io"
 	"net/http"
 	"strconv"
 
-	"github.com/WJQSERVER-STUDIO/go-utils/limitreader"
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
@@ -20,6 +21,14 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 		err  error
 	)
 
+	ctx = withOutboundRoute(ctx, matcher)
+	ctx = withRedirectPolicy(ctx)
+
+	var expectedSum string
+	if cfg.Checksum.Enabled && isDownloadMatcher(matcher) {
+		u, expectedSum = takeDigestQuery(u)
+	}
+
 	go func() {
 		<-ctx.Done()
 		if resp != nil && resp.Body != nil {
@@ -30,7 +39,13 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 		}
 	}()
 
-	rb := client.NewRequestBuilder(string(c.Request.Method()), u)
+	useCache := cacheable(c, matcher, u)
+	if useCache && serveFromCache(ctx, c, cfg, u, matcher) {
+		return
+	}
+
+	cl := clientFor(client, c)
+	rb := cl.NewRequestBuilder(string(c.Request.Method()), u)
 	rb.NoDefaultHeaders()
 	rb.SetBody(c.Request.BodyStream())
 	rb.WithContext(ctx)
@@ -41,21 +56,61 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 		return
 	}
 
+	// 声明了长度的请求体按原长度转发, 避免以 chunked 发往上游
+	if n := c.Request.Header.ContentLength(); n > 0 {
+		req.ContentLength = int64(n)
+	}
+
 	setRequestHeaders(c, req, cfg, matcher)
 	AuthPassThrough(c, cfg, req)
 
-	resp, err = client.Do(req)
+	var (
+		apiKey   string
+		apiEntry *apicache.Entry
+	)
+	if apiCacheable(c, matcher) {
+		apiKey = apiCacheKey(req)
+		var served bool
+		if apiEntry, served = lookupAPICache(c, cfg, req, u, matcher, apiKey); served {
+			return
+		}
+	}
+	// 令牌池在缓存键计算之后注入, 匿名客户端共享同一组缓存条目
+	poolToken := injectPoolToken(req, matcher)
+
+	leader, coalesced := true, coalescable(c, matcher)
+	if coalesced {
+		resp, leader, err = coalesceDo(ctx, cl, u, req, cfg)
+	} else {
+		resp, err = upstreamDo(cl, req, cfg)
+	}
 	if err != nil {
-		HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+		HandleUpstreamError(c, u, err)
+		return
+	}
+	reportPoolToken(poolToken, resp)
+
+	// 缓存条目经上游确认未变更
+	if apiEntry != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		serveAPIEntry(c, cfg, u, matcher, revalidateAPIEntry(apiKey, apiEntry, resp.Header), "REVALIDATED")
 		return
 	}
 
 	// 错误处理(404)
 	if resp.StatusCode == 404 {
+		resp.Body.Close()
 		ErrorPage(c, NewErrorWithStatusLookup(404, "Page Not Found (From Github)"))
 		return
 	}
 
+	if reason := checkContentPolicy(matcher, u, resp.StatusCode, resp.Header); reason != "" {
+		resp.Body.Close()
+		ErrorPage(c, NewErrorWithStatusLookup(403, reason).WithCode("CONTENT_BLOCKED"))
+		logInfo("%s %s %s %s %s Content-Policy: %s", c.ClientIP(), c.Method(), u, c.UserAgent(), c.Request.Header.GetProtocol(), reason)
+		return
+	}
+
 	var (
 		bodySize      int
 		contentLength string
@@ -70,14 +125,8 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 			logWarning("%s %s %s %s %s Content-Length header is not a valid integer: %v", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), err)
 			bodySize = -1
 		}
-		if err == nil && bodySize > sizelimit {
-			finalURL := resp.Request.URL.String()
-			err = resp.Body.Close()
-			if err != nil {
-				logError("Failed to close response body: %v", err)
-			}
-			c.Redirect(301, []byte(finalURL))
-			logWarning("%s %s %s %s %s Final-URL: %s Size-Limit-Exceeded: %d", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), finalURL, bodySize)
+		if err == nil && bodySize > sizelimit && !sizeLimitExempt(c, cfg, matcher) {
+			handleOverLimit(c, cfg, resp, matcher, bodySize)
 			return
 		}
 	}
@@ -90,27 +139,63 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 			}
 		}
 	}
+	rewriteLocation(c, resp)
+	rewriteLinkHeader(c, cfg, resp.Header, matcher)
 
-	switch cfg.Server.Cors {
-	case "*":
-		c.Header("Access-Control-Allow-Origin", "*")
-	case "":
-		c.Header("Access-Control-Allow-Origin", "*")
-	case "nil":
-		c.Header("Access-Control-Allow-Origin", "")
-	default:
-		c.Header("Access-Control-Allow-Origin", cfg.Server.Cors)
-	}
+	setCorsHeader(c, cfg)
+	applyResponseHeaderRules(c, u, matcher)
 
 	c.Status(resp.StatusCode)
 
-	bodyReader := resp.Body
+	var bodyReader io.ReadCloser = resp.Body
+
+	// 大文件改为分段并发下载, 合并请求的共享响应体不参与
+	if !coalesced {
+		if validator, ok := segmentable(c, cfg, resp, matcher); ok {
+			bodyReader = newSegmentedBody(ctx, cl, cfg, resp, validator)
+		}
+	}
+
+	// 未声明长度的响应边读边计数
+	if (contentLength == "" || bodySize < 0) && !sizeLimitExempt(c, cfg, matcher) {
+		bodyReader = newLimitedBody(bodyReader, sizelimit, u)
+	}
+
+	if apiKey != "" {
+		bodyReader = teeToAPICache(c, resp, bodyReader, apiKey)
+	}
+
+	// 缓存写入原始内容, 链接改写在其后进行
+	if apiRewritable(matcher, resp.StatusCode, resp.Header) && string(c.Request.Method()) != http.MethodHead {
+		if contentLength == "" {
+			bodySize = -1
+		}
+		bodyReader, bodySize = rewriteAPIBody(c, cfg, resp.Header, bodyReader, bodySize)
+		contentLength = ""
+		if bodySize >= 0 {
+			contentLength = strconv.Itoa(bodySize)
+		}
+	}
+
+	// 摘要以 trailer 返回时需使用 chunked 传输
+	var (
+		forceChunked bool
+		verified     func() bool
+	)
+	if cfg.Checksum.Enabled && isDownloadMatcher(matcher) {
+		bodyReader, forceChunked = wrapChecksum(c, cl, cfg, resp, bodyReader, u, expectedSum)
+		if r, ok := bodyReader.(*checksum.Reader); ok {
+			verified = func() bool { return r.Result() != checksum.ResultMismatch }
+		}
+	}
 
-	if cfg.RateLimit.BandwidthLimit.Enabled {
-		bodyReader = limitreader.NewRateLimitedReader(bodyReader, bandwidthLimit, int(bandwidthBurst), ctx)
+	// 合并请求时仅由 leader 写入缓存, 缓存在校验之后写入, 校验不一致的内容不会被缓存
+	if useCache && leader {
+		bodyReader = teeToCache(c, resp, bodyReader, u, bodySize, verified)
 	}
 
-	if MatcherShell(u) && matchString(matcher, matchedMatchers) && cfg.Shell.Editor {
+	bodyReader = limitBandwidth(ctx, bodyReader)
+	if MatcherShell(u) && shellEditable(u, matcher) && cfg.Shell.Editor {
 		// 判断body是不是gzip
 		var compress string
 		if resp.Header.Get("Content-Encoding") == "gzip" {
@@ -131,7 +216,7 @@ func ChunkedProxyRequest(ctx context.Context, c *app.RequestContext, u string, c
 		}
 	} else {
 
-		if contentLength != "" {
+		if contentLength != "" && !forceChunked {
 			c.SetBodyStream(bodyReader, bodySize)
 			return
 		}
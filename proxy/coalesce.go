@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"context"
+	"ghproxy/coalesce"
+	"ghproxy/config"
+	"net/http"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var requestGroup *coalesce.Group
+
+// InitCoalesce 初始化请求合并
+func InitCoalesce(cfg *config.Config) {
+	if !cfg.Coalesce.Enabled {
+		return
+	}
+	requestGroup = coalesce.New(cfg.Coalesce.SpillDir)
+	logInfo("Request coalescing enabled, spill dir: %s", cfg.Coalesce.SpillDir)
+}
+
+// coalescable 判断请求是否可与其他相同请求合并
+// 带有条件请求头或 Authorization 的请求响应因人而异, 不参与合并
+func coalescable(c *app.RequestContext, matcher string) bool {
+	if requestGroup == nil || !isDownloadMatcher(matcher) {
+		return false
+	}
+	if string(c.Request.Method()) != http.MethodGet {
+		return false
+	}
+	for _, h := range []string{"Range", "If-Range", "If-None-Match", "If-Modified-Since", "Authorization"} {
+		if len(c.Request.Header.Peek(h)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// coalesceDo 合并相同URL的并发上游请求, leader 为实际发起请求的一方
+// 响应由所有等待者共享, 因此移除 leader 的 Accept-Encoding, 交由 http 客户端协商并透明解压,
+// 避免把仅 leader 能解码的编码交给其他等待者; Accept 可能影响上游返回的内容, 计入合并键
+func coalesceDo(ctx context.Context, cl *httpc.Client, u string, req *http.Request, cfg *config.Config) (*http.Response, bool, error) {
+	req.Header.Del("Accept-Encoding")
+	key := u
+	if accept := req.Header.Get("Accept"); accept != "" {
+		key += " Accept: " + accept
+	}
+	return requestGroup.Do(ctx, key, func(fetchCtx context.Context) (*http.Response, error) {
+		// fetchCtx 与客户端上下文无关, 需带上出站路由与重定向策略
+		if matcher, ok := ctx.Value(outboundRouteKey{}).(string); ok {
+			fetchCtx = withOutboundRoute(fetchCtx, matcher)
+		}
+		if _, ok := ctx.Value(redirectPolicyKey{}).(bool); ok {
+			fetchCtx = withRedirectPolicy(fetchCtx)
+		}
+		return upstreamDo(cl, req.WithContext(fetchCtx), cfg)
+	})
+}
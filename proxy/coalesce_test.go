@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"context"
+	"ghproxy/coalesce"
+	"ghproxy/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+func TestCoalescedNotFoundReleasesSpill(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not Found", http.StatusNotFound)
+	}))
+	defer upstream.Close()
+
+	cfg := config.DefaultConfig()
+	initHTTPClient(cfg)
+	spillDir := t.TempDir()
+	requestGroup = coalesce.New(spillDir)
+	defer func() { requestGroup = nil }()
+
+	c := app.NewContext(0)
+	c.Request.SetRequestURI("/")
+	c.Request.Header.SetMethod(http.MethodGet)
+	c.Request.Header.Set("Accept", "application/json")
+	ChunkedProxyRequest(context.Background(), c, upstream.URL+"/owner/repo/releases/download/v1/a.zip", cfg, "releases")
+	if c.Response.StatusCode() != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", c.Response.StatusCode())
+	}
+
+	// 溢出文件在上游读取结束且所有读取方关闭后删除
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, err := os.ReadDir(spillDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("spill files left behind: %v", entries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCoalesceNormalizesEncoding(t *testing.T) {
+	const content = "release asset content"
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write([]byte(content))
+			gz.Close()
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer upstream.Close()
+
+	cfg := config.DefaultConfig()
+	initHTTPClient(cfg)
+	requestGroup = coalesce.New(t.TempDir())
+	defer func() { requestGroup = nil }()
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+	}{
+		{"client accepts gzip", "gzip, deflate, br"},
+		{"client accepts identity only", "identity"},
+		{"no accept encoding", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, upstream.URL+"/owner/repo/releases/download/v1/a.txt", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			resp, _, err := coalesceDo(context.Background(), client, upstream.URL, req, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 共享给所有等待者的响应不应带有由 leader 协商的编码
+			if enc := resp.Header.Get("Content-Encoding"); enc != "" || string(body) != content {
+				t.Fatalf("Content-Encoding = %q, body = %q; want identity %q", enc, body, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"fmt"
+	"ghproxy/config"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// contentRule 预处理后的文件类型策略规则
+type contentRule struct {
+	name         string
+	matchers     map[string]struct{} // 为空表示作用于所有matcher
+	allow        bool
+	extensions   []string
+	contentTypes []string
+}
+
+var contentRules []*contentRule
+
+// InitContentPolicy 解析文件类型与 Content-Type 策略
+func InitContentPolicy(cfg *config.Config) error {
+	contentRules = nil
+	for i, r := range cfg.ContentPolicy.Rules {
+		rule := &contentRule{name: r.Name}
+		if rule.name == "" {
+			rule.name = fmt.Sprintf("rules[%d]", i)
+		}
+		switch strings.ToLower(r.Action) {
+		case "allow":
+			rule.allow = true
+		case "deny":
+		default:
+			return fmt.Errorf("contentPolicy.rules[%d]: unknown action %q, must be allow or deny", i, r.Action)
+		}
+		if len(r.Matchers) > 0 {
+			rule.matchers = make(map[string]struct{}, len(r.Matchers))
+			for _, m := range r.Matchers {
+				rule.matchers[m] = struct{}{}
+			}
+		}
+		for _, ext := range r.Extensions {
+			ext = strings.ToLower(strings.TrimSpace(ext))
+			if ext != "" && !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			rule.extensions = append(rule.extensions, ext)
+		}
+		for _, ct := range r.ContentTypes {
+			rule.contentTypes = append(rule.contentTypes, strings.ToLower(strings.TrimSpace(ct)))
+		}
+		contentRules = append(contentRules, rule)
+	}
+	if len(contentRules) > 0 {
+		logInfo("Content policy rules loaded: %d", len(contentRules))
+	}
+	return nil
+}
+
+func (r *contentRule) appliesTo(matcher string) bool {
+	if r.matchers == nil {
+		return true
+	}
+	_, ok := r.matchers[matcher]
+	return ok
+}
+
+// match 扩展名或 Content-Type 任一命中即视为命中, 返回命中说明
+func (r *contentRule) match(filename string, contentType string) (string, bool) {
+	for _, ext := range r.extensions {
+		if filename != "" && strings.HasSuffix(filename, ext) {
+			return "extension " + ext, true
+		}
+	}
+	if contentType == "" {
+		return "", false
+	}
+	for _, pattern := range r.contentTypes {
+		if matchContentType(pattern, contentType) {
+			return "content type " + contentType, true
+		}
+	}
+	return "", false
+}
+
+// matchContentType 支持 "text/*" 与 "*/*" 通配
+func matchContentType(pattern string, contentType string) bool {
+	if pattern == "*/*" || pattern == contentType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(contentType, prefix+"/")
+	}
+	return false
+}
+
+// checkContentPolicy 按顺序匹配规则, 返回拦截原因, 为空表示放行
+// 重定向响应仅按扩展名拦截, 以免跳转后的签名地址绕过策略; 其他非 2xx 响应不检查
+// 某 matcher 配置了 allow 规则时, 未命中任何 allow 规则的 2xx 响应同样被拦截
+func checkContentPolicy(matcher string, u string, status int, header http.Header) string {
+	if len(contentRules) == 0 || status < 200 || status >= 400 {
+		return ""
+	}
+	redirect := status >= 300
+	filename := contentFilename(u, header)
+	var contentType string
+	if !redirect {
+		contentType, _, _ = mime.ParseMediaType(header.Get("Content-Type"))
+	}
+
+	var allowRules []string
+	for _, rule := range contentRules {
+		if !rule.appliesTo(matcher) {
+			continue
+		}
+		if rule.allow {
+			allowRules = append(allowRules, rule.name)
+		}
+		what, ok := rule.match(filename, contentType)
+		if !ok {
+			continue
+		}
+		if rule.allow {
+			return ""
+		}
+		return fmt.Sprintf("Blocked by content policy rule %q: %s", rule.name, what)
+	}
+	if len(allowRules) > 0 && !redirect {
+		return fmt.Sprintf("Blocked by content policy: %s (content type %q) is not allowed by rule %s", filename, contentType, strings.Join(allowRules, ", "))
+	}
+	return ""
+}
+
+// contentFilename 优先取 Content-Disposition 中的文件名, CDN 签名地址的路径中不含文件名
+func contentFilename(u string, header http.Header) string {
+	if cd := header.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil && params["filename"] != "" {
+			return strings.ToLower(params["filename"])
+		}
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(path.Base(parsed.Path))
+}
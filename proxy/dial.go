@@ -6,10 +6,13 @@
 package proxy
 
 import (
+	"context"
 	"ghproxy/config"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"golang.org/x/net/proxy"
 )
@@ -21,6 +24,13 @@ func initTransport(cfg *config.Config, transport *http.Transport) {
 		return
 	}
 
+	// 配置了代理池时按策略与路由选择代理
+	if outboundPool != nil {
+		transport.Proxy = outboundProxy
+		logInfo("Using outbound proxy pool, strategy: %s, proxies: %d", outboundPool.Strategy(), len(cfg.Outbound.Pool))
+		return
+	}
+
 	// 如果代理 URL 未设置，使用环境变量中的代理配置
 	if cfg.Outbound.Url == "" {
 		transport.Proxy = http.ProxyFromEnvironment
@@ -62,9 +72,28 @@ func initTransport(cfg *config.Config, transport *http.Transport) {
 	}
 }
 
+// applyDialers 按顺序为传输层叠加源地址绑定、DNS解析、代理池连接统计与拨号超时
+func applyDialers(cfg *config.Config, transport *http.Transport) {
+	applySourceAddrs(transport)
+	applyResolver(transport)
+	applyOutboundPool(transport)
+	applyTimeouts(cfg, transport)
+}
+
+// transportDialContext 返回传输层当前的拨号函数, 未设置时返回默认拨号器
+func transportDialContext(transport *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if transport.DialContext != nil {
+		return transport.DialContext
+	}
+	return (&net.Dialer{KeepAlive: 30 * time.Second}).DialContext
+}
+
 // newProxyDial 创建一个 SOCKS5 代理拨号器
 func newProxyDial(proxyUrls string) proxy.Dialer {
 	var proxyDialer proxy.Dialer = proxy.Direct // 初始为直接连接，不使用代理
+	if len(sourceAddrs) > 0 {
+		proxyDialer = sourceDialer{} // 绑定源地址连接首跳代理
+	}
 
 	// 支持多个代理 URL（以逗号分隔）
 	for _, proxyUrl := range strings.Split(proxyUrls, ",") {
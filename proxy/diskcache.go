@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"ghproxy/config"
+	"ghproxy/diskcache"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var diskCache *diskcache.Cache
+
+// InitDiskCache 初始化磁盘缓存
+func InitDiskCache(cfg *config.Config) (*diskcache.Cache, error) {
+	if !cfg.Cache.Enabled {
+		return nil, nil
+	}
+	var err error
+	diskCache, err = diskcache.New(
+		cfg.Cache.Dir,
+		int64(cfg.Cache.MaxSize)*1024*1024,
+		time.Duration(cfg.Cache.TTL)*time.Minute,
+	)
+	if err != nil {
+		return nil, err
+	}
+	logInfo("Disk cache enabled at %s, max size: %d MB, ttl: %d min", cfg.Cache.Dir, cfg.Cache.MaxSize, cfg.Cache.TTL)
+	return diskCache, nil
+}
+
+// DiskCacheStats 返回磁盘缓存状态
+func DiskCacheStats() (bool, int, int64) {
+	if diskCache == nil {
+		return false, 0, 0
+	}
+	entries, size := diskCache.Stats()
+	return true, entries, size
+}
+
+// cacheable 判断请求是否可使用磁盘缓存
+// 仅缓存下载类的 GET 请求, 携带 Authorization 的请求不缓存以免泄漏私有资源
+// CDN 签名地址每次均不同, 缓存无法命中, 同样不缓存
+func cacheable(c *app.RequestContext, matcher string, u string) bool {
+	if diskCache == nil || !isDownloadMatcher(matcher) || isSignedAssetURL(u) {
+		return false
+	}
+	if string(c.Request.Method()) != http.MethodGet {
+		return false
+	}
+	return len(c.Request.Header.Peek("Authorization")) == 0
+}
+
+// isImmutableAsset 判断是否为不可变的release资源, 不可变资源不受TTL约束
+func isImmutableAsset(u string) bool {
+	return strings.Contains(u, "/releases/download/") && !strings.Contains(u, "/releases/latest/")
+}
+
+// serveFromCache 命中缓存时直接响应, 支持 Range 与 If-None-Match
+func serveFromCache(ctx context.Context, c *app.RequestContext, cfg *config.Config, u string, matcher string) bool {
+	f, meta, ok := diskCache.Open(u)
+	if !ok {
+		return false
+	}
+
+	// 缓存内容同样受文件类型策略约束
+	header := http.Header{}
+	header.Set("Content-Type", meta.ContentType)
+	header.Set("Content-Disposition", meta.ContentDisposition)
+	if reason := checkContentPolicy(matcher, u, http.StatusOK, header); reason != "" {
+		f.Close()
+		ErrorPage(c, NewErrorWithStatusLookup(403, reason).WithCode("CONTENT_BLOCKED"))
+		logInfo("%s %s %s %s %s Content-Policy: %s", c.ClientIP(), c.Method(), u, c.UserAgent(), c.Request.Header.GetProtocol(), reason)
+		return true
+	}
+
+	etag := meta.ETag
+	if etag == "" {
+		etag = fmt.Sprintf("\"%x-%x\"", meta.CreatedAt.Unix(), meta.Size)
+	}
+
+	c.Header("X-GHProxy-Cache", "HIT")
+	c.Header("ETag", etag)
+	c.Header("Accept-Ranges", "bytes")
+	if meta.LastModified != "" {
+		c.Header("Last-Modified", meta.LastModified)
+	}
+	setCorsHeader(c, cfg)
+
+	if inm := string(c.Request.Header.Peek("If-None-Match")); inm != "" && etagMatch(inm, etag) {
+		f.Close()
+		applyResponseHeaderRules(c, u, matcher)
+		c.Status(http.StatusNotModified)
+		logDebug("%s %s %s Disk cache HIT 304", c.ClientIP(), c.Method(), u)
+		return true
+	}
+
+	if meta.ContentType != "" {
+		c.Header("Content-Type", meta.ContentType)
+	}
+	if meta.ContentDisposition != "" {
+		c.Header("Content-Disposition", meta.ContentDisposition)
+	}
+
+	var (
+		start  int64
+		length = meta.Size
+		status = http.StatusOK
+	)
+	if rangeHeader := string(c.Request.Header.Peek("Range")); rangeHeader != "" && ifRangeMatch(c, etag, meta.LastModified) {
+		var satisfiable bool
+		start, length, satisfiable = parseRange(rangeHeader, meta.Size)
+		if !satisfiable {
+			f.Close()
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", meta.Size))
+			ErrorPage(c, NewErrorWithStatusLookup(http.StatusRequestedRangeNotSatisfiable, "Requested Range Not Satisfiable"))
+			return true
+		}
+		if start != 0 || length != meta.Size {
+			status = http.StatusPartialContent
+			c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, meta.Size))
+		}
+	}
+
+	applyResponseHeaderRules(c, u, matcher)
+	c.Status(status)
+	if string(c.Request.Method()) == http.MethodHead {
+		f.Close()
+		c.Header("Content-Length", strconv.FormatInt(length, 10))
+		return true
+	}
+
+	bodyReader := limitBandwidth(ctx, io.NopCloser(io.NewSectionReader(f, start, length)))
+	c.SetBodyStream(&fileBodyReader{Reader: bodyReader, f: f}, int(length))
+	logDebug("%s %s %s Disk cache HIT", c.ClientIP(), c.Method(), u)
+	return true
+}
+
+// teeToCache 将上游响应写入磁盘缓存, 不满足条件时原样返回
+// valid 不为 nil 时, 仅在其返回 true 时提交缓存项
+func teeToCache(c *app.RequestContext, resp *http.Response, body io.ReadCloser, u string, bodySize int, valid func() bool) io.ReadCloser {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Encoding") != "" {
+		return body
+	}
+	if len(c.Request.Header.Peek("Range")) != 0 {
+		return body
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return body
+	}
+
+	expectedSize := int64(-1)
+	if bodySize >= 0 && resp.Header.Get("Content-Length") != "" {
+		expectedSize = int64(bodySize)
+	}
+	meta := diskcache.Meta{
+		Key:                resp.Request.URL.String(),
+		RequestURL:         u,
+		ContentType:        resp.Header.Get("Content-Type"),
+		ContentDisposition: resp.Header.Get("Content-Disposition"),
+		ETag:               resp.Header.Get("ETag"),
+		LastModified:       resp.Header.Get("Last-Modified"),
+		Immutable:          isImmutableAsset(u),
+	}
+	c.Header("X-GHProxy-Cache", "MISS")
+	return diskCache.Tee(body, meta, expectedSize, valid)
+}
+
+// fileBodyReader 在响应结束时关闭缓存文件
+type fileBodyReader struct {
+	io.Reader
+	f *os.File
+}
+
+func (r *fileBodyReader) Close() error {
+	return r.f.Close()
+}
+
+// etagMatch 比较 If-None-Match 与 ETag (弱比较)
+func etagMatch(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ifRangeMatch 检查 If-Range 条件, 不满足时应返回完整内容
+func ifRangeMatch(c *app.RequestContext, etag string, lastModified string) bool {
+	ifRange := string(c.Request.Header.Peek("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/") {
+		return !strings.HasPrefix(ifRange, "W/") && ifRange == etag
+	}
+	return lastModified != "" && ifRange == lastModified
+}
+
+// parseRange 解析单段 Range 请求头, 多段请求按完整内容处理
+func parseRange(header string, size int64) (start int64, length int64, satisfiable bool) {
+	if !strings.HasPrefix(header, "bytes=") || strings.Contains(header, ",") {
+		return 0, size, true
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	startStr, endStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, size, true
+	}
+	startStr = strings.TrimSpace(startStr)
+	endStr = strings.TrimSpace(endStr)
+
+	if startStr == "" {
+		// bytes=-N 表示最后N个字节
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, size > 0
+	}
+
+	s, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || s < 0 || s >= size {
+		return 0, 0, false
+	}
+	e := size - 1
+	if endStr != "" {
+		e, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || e < s {
+			return 0, 0, false
+		}
+		if e >= size {
+			e = size - 1
+		}
+	}
+	return s, e - s + 1, true
+}
@@ -13,7 +13,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/WJQSERVER-STUDIO/go-utils/limitreader"
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
@@ -93,7 +92,7 @@ func GhcrToTarget(ctx context.Context, c *app.RequestContext, cfg *config.Config
 		}
 
 	} else {
-		ErrorPage(c, NewErrorWithStatusLookup(403, "Docker is not Allowed"))
+		ErrorPage(c, NewErrorWithStatusLookup(403, "Docker is not Allowed").WithCode("DOCKER_DISABLED"))
 		return
 	}
 }
@@ -107,6 +106,8 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 		err    error
 	)
 
+	ctx = withOutboundRoute(ctx, "docker")
+
 	go func() {
 		<-ctx.Done()
 		if resp != nil && resp.Body != nil {
@@ -118,8 +119,9 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 	}()
 
 	method = c.Request.Method()
+	cl := clientFor(ghcrclient, c)
 
-	rb := ghcrclient.NewRequestBuilder(string(method), u)
+	rb := cl.NewRequestBuilder(string(method), u)
 	rb.NoDefaultHeaders()
 	rb.SetBody(c.Request.BodyStream())
 	rb.WithContext(ctx)
@@ -135,6 +137,7 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 		headerValue := string(value)
 		req.Header.Add(headerKey, headerValue)
 	})
+	applyRequestHeaderRules(c, req.Header, u, "docker")
 
 	req.Header.Set("Host", target)
 	if image != nil {
@@ -145,9 +148,9 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 		}
 	}
 
-	resp, err = ghcrclient.Do(req)
+	resp, err = breakerDo(cl, req)
 	if err != nil {
-		HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+		HandleUpstreamError(c, u, err)
 		return
 	}
 
@@ -168,7 +171,7 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 				cache.Put(image.Image, token)
 			}
 
-			rb := ghcrclient.NewRequestBuilder(string(method), u)
+			rb := cl.NewRequestBuilder(string(method), u)
 			rb.NoDefaultHeaders()
 			rb.SetBody(c.Request.BodyStream())
 			rb.WithContext(ctx)
@@ -190,9 +193,9 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 				req.Header.Set("Authorization", "Bearer "+token)
 			}
 
-			resp, err = ghcrclient.Do(req)
+			resp, err = breakerDo(cl, req)
 			if err != nil {
-				HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+				HandleUpstreamError(c, u, err)
 				return
 			}
 		}
@@ -217,14 +220,8 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 			logWarning("%s %s %s %s %s Content-Length header is not a valid integer: %v", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), err)
 			bodySize = -1
 		}
-		if err == nil && bodySize > sizelimit {
-			finalURL := resp.Request.URL.String()
-			err = resp.Body.Close()
-			if err != nil {
-				logError("Failed to close response body: %v", err)
-			}
-			c.Redirect(301, []byte(finalURL))
-			logWarning("%s %s %s %s %s Final-URL: %s Size-Limit-Exceeded: %d", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), finalURL, bodySize)
+		if err == nil && bodySize > sizelimit && !sizeLimitExempt(c, cfg, "docker") {
+			handleOverLimit(c, cfg, resp, "docker", bodySize)
 			return
 		}
 	}
@@ -235,15 +232,18 @@ func GhcrRequest(ctx context.Context, c *app.RequestContext, u string, image *im
 			c.Response.Header.Add(key, value)
 		}
 	}
+	applyResponseHeaderRules(c, u, "docker")
 
 	c.Status(resp.StatusCode)
 
 	bodyReader := resp.Body
 
-	if cfg.RateLimit.BandwidthLimit.Enabled {
-		bodyReader = limitreader.NewRateLimitedReader(bodyReader, bandwidthLimit, int(bandwidthBurst), ctx)
+	// 未声明长度的响应边读边计数
+	if (contentLength == "" || bodySize < 0) && !sizeLimitExempt(c, cfg, "docker") {
+		bodyReader = newLimitedBody(bodyReader, sizelimit, u)
 	}
 
+	bodyReader = limitBandwidth(ctx, bodyReader)
 	if contentLength != "" {
 		c.SetBodyStream(bodyReader, bodySize)
 		return
@@ -261,7 +261,8 @@ func ChallengeReq(target string, image *imageInfo, ctx context.Context, c *app.R
 	var req401 *http.Request
 	var err error
 
-	rb401 := ghcrclient.NewRequestBuilder("GET", "https://"+target+"/v2/")
+	cl := clientFor(ghcrclient, c)
+	rb401 := cl.NewRequestBuilder("GET", "https://"+target+"/v2/")
 	rb401.NoDefaultHeaders()
 	rb401.WithContext(ctx)
 	rb401.AddHeader("User-Agent", "docker/28.1.1 go/go1.23.8 git-commit/01f442b kernel/6.12.25-amd64 os/linux arch/amd64 UpstreamClient(Docker-Client/28.1.1 ")
@@ -272,9 +273,9 @@ func ChallengeReq(target string, image *imageInfo, ctx context.Context, c *app.R
 	}
 	req401.Header.Set("Host", target)
 
-	resp401, err = ghcrclient.Do(req401)
+	resp401, err = breakerDo(cl, req401)
 	if err != nil {
-		HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+		HandleUpstreamError(c, req401.URL.String(), err)
 		return
 	}
 	defer resp401.Body.Close()
@@ -286,7 +287,7 @@ func ChallengeReq(target string, image *imageInfo, ctx context.Context, c *app.R
 
 	scope := fmt.Sprintf("repository:%s:pull", image.Image)
 
-	getAuthRB := ghcrclient.NewRequestBuilder("GET", bearer.Realm).
+	getAuthRB := cl.NewRequestBuilder("GET", bearer.Realm).
 		NoDefaultHeaders().
 		WithContext(ctx).
 		AddHeader("User-Agent", "docker/28.1.1 go/go1.23.8 git-commit/01f442b kernel/6.12.25-amd64 os/linux arch/amd64 UpstreamClient(Docker-Client/28.1.1 ").
@@ -300,7 +301,7 @@ func ChallengeReq(target string, image *imageInfo, ctx context.Context, c *app.R
 		return
 	}
 
-	authResp, err := ghcrclient.Do(getAuthReq)
+	authResp, err := breakerDo(cl, getAuthReq)
 	if err != nil {
 		logError("Failed to send request: %v", err)
 		return
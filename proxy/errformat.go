@@ -0,0 +1,170 @@
+package proxy
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+type errorFormat int
+
+const (
+	errorFormatHTML     errorFormat = iota
+	errorFormatJSON                 // application/json
+	errorFormatPlain                // curl / wget 等命令行工具
+	errorFormatGit                  // git smart-HTTP 的 ERR 数据包
+	errorFormatRegistry             // /v2/ 下的 registry 错误格式
+)
+
+// statusErrorCode 未在 statusErrorMap 中的状态码对应的错误码
+func statusErrorCode(statusCode int) string {
+	switch statusCode {
+	case http.StatusMethodNotAllowed:
+		return "METHOD_NOT_ALLOWED"
+	case http.StatusRequestedRangeNotSatisfiable:
+		return "RANGE_NOT_SATISFIABLE"
+	case http.StatusBadGateway:
+		return "BAD_GATEWAY"
+	case http.StatusGatewayTimeout:
+		return "GATEWAY_TIMEOUT"
+	}
+	if statusCode >= 500 {
+		return "INTERNAL_ERROR"
+	}
+	return "ERROR"
+}
+
+// registryErrorCodes 错误码对应的 registry 错误码, 未列出的按状态码映射
+var registryErrorCodes = map[int]string{
+	http.StatusUnauthorized:     "UNAUTHORIZED",
+	http.StatusForbidden:        "DENIED",
+	http.StatusNotFound:         "NAME_UNKNOWN",
+	http.StatusMethodNotAllowed: "UNSUPPORTED",
+	http.StatusTooManyRequests:  "TOOMANYREQUESTS",
+}
+
+// negotiateErrorFormat 按请求路径, Accept 与 User-Agent 选择错误响应格式
+func negotiateErrorFormat(c *app.RequestContext) errorFormat {
+	path := string(c.Path())
+	if path == "/v2" || strings.HasPrefix(path, "/v2/") {
+		return errorFormatRegistry
+	}
+	if gitService(c) != "" {
+		return errorFormatGit
+	}
+	switch preferredErrorMediaType(string(c.Request.Header.Peek("Accept"))) {
+	case "text/html":
+		return errorFormatHTML
+	case "application/json":
+		return errorFormatJSON
+	case "text/plain":
+		return errorFormatPlain
+	}
+	// Accept 为空或仅为通配时按 User-Agent 判断, 仅浏览器返回 HTML
+	if strings.HasPrefix(string(c.UserAgent()), "Mozilla/") {
+		return errorFormatHTML
+	}
+	return errorFormatPlain
+}
+
+// preferredErrorMediaType 返回 Accept 中显式列出且权重最高的可用格式, 通配符不参与
+func preferredErrorMediaType(accept string) string {
+	var (
+		best  string
+		bestQ = 0.0
+	)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(mediaType, "+json") {
+			mediaType = "application/json"
+		}
+		if mediaType != "text/html" && mediaType != "application/json" && mediaType != "text/plain" {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
+
+// gitService 识别 git smart-HTTP 请求, 返回其服务名
+func gitService(c *app.RequestContext) string {
+	path := string(c.Path())
+	for _, service := range []string{"git-upload-pack", "git-receive-pack"} {
+		if strings.HasSuffix(path, "/"+service) {
+			return service
+		}
+	}
+	if strings.HasSuffix(path, "/info/refs") {
+		if service := c.Query("service"); service == "git-upload-pack" || service == "git-receive-pack" {
+			return service
+		}
+	}
+	return ""
+}
+
+// writeErrorFormat 以非 HTML 格式写出错误
+func writeErrorFormat(c *app.RequestContext, errInfo *GHProxyErrors, format errorFormat) {
+	code := errInfo.Code
+	if code == "" {
+		code = statusErrorCode(errInfo.StatusCode)
+	}
+	message := errInfo.ErrorMessage
+	if message == "" {
+		message = errInfo.StatusDesc
+	}
+
+	switch format {
+	case errorFormatJSON:
+		c.JSON(errInfo.StatusCode, map[string]interface{}{
+			"status": errInfo.StatusCode,
+			"code":   code,
+			"title":  errInfo.StatusText,
+			"error":  message,
+			"help":   errInfo.HelpInfo,
+		})
+	case errorFormatRegistry:
+		registryCode, ok := registryErrorCodes[errInfo.StatusCode]
+		if !ok {
+			registryCode = code
+		}
+		c.JSON(errInfo.StatusCode, map[string]interface{}{
+			"errors": []map[string]interface{}{{
+				"code":    registryCode,
+				"message": message,
+				"detail":  map[string]string{"code": code},
+			}},
+		})
+	case errorFormatGit:
+		// git 仅在 200 响应中解析 ERR 数据包, 并将其作为 "remote error" 显示
+		service := gitService(c)
+		contentType := "application/x-" + service + "-result"
+		if strings.HasSuffix(string(c.Path()), "/info/refs") {
+			contentType = "application/x-" + service + "-advertisement"
+		}
+		c.Header("X-GHProxy-Error", code)
+		c.Header("Cache-Control", "no-cache")
+		line := fmt.Sprintf("ERR %d %s: %s\n", errInfo.StatusCode, code, message)
+		c.Data(http.StatusOK, contentType, []byte(fmt.Sprintf("%04x%s", len(line)+4, line)))
+	default:
+		text := fmt.Sprintf("Error %d %s: %s\n", errInfo.StatusCode, code, message)
+		if errInfo.HelpInfo != "" {
+			text += errInfo.HelpInfo + "\n"
+		}
+		c.Data(errInfo.StatusCode, "text/plain; charset=utf-8", []byte(text))
+	}
+}
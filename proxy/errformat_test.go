@@ -0,0 +1,33 @@
+package proxy
+
+import "testing"
+
+func TestPreferredErrorMediaType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", ""},
+		{"*/*", ""},
+		{"text/*", ""},
+		{"text/html", "text/html"},
+		{"application/json", "application/json"},
+		{"text/plain", "text/plain"},
+		{"application/vnd.github+json", "application/json"},
+		{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"application/json;q=0.5, text/plain", "text/plain"},
+		{"text/plain;q=0.5, application/json;q=0.8", "application/json"},
+		{"text/html, application/json", "text/html"},
+		{"text/html;q=0, */*", ""},
+		{"image/png, application/octet-stream", ""},
+		{"TEXT/HTML", "text/html"},
+		{"text/plain; charset=utf-8", "text/plain"},
+		{"application/json;q=invalid, text/plain;q=0.9", "application/json"},
+		{"bad/;;, text/plain", "text/plain"},
+	}
+	for _, tt := range tests {
+		if got := preferredErrorMediaType(tt.accept); got != tt.want {
+			t.Errorf("preferredErrorMediaType(%q) = %q, want %q", tt.accept, got, tt.want)
+		}
+	}
+}
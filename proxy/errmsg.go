@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"fmt"
+	"ghproxy/config"
+	"ghproxy/i18n"
+	"strconv"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var (
+	errorCatalog  *i18n.Catalog
+	errorHelpInfo map[int]string // 运维按状态码自定义的帮助信息
+)
+
+// InitErrorMessages 加载错误信息目录及自定义帮助信息
+func InitErrorMessages(cfg *config.Config) error {
+	catalog, err := i18n.New(cfg.Pages.Language, cfg.Pages.LocalesDir)
+	if err != nil {
+		return fmt.Errorf("pages: %w", err)
+	}
+	helpInfo := make(map[int]string, len(cfg.Pages.HelpInfo))
+	for status, text := range cfg.Pages.HelpInfo {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			return fmt.Errorf("pages.helpInfo: invalid status code %q", status)
+		}
+		helpInfo[code] = text
+	}
+	errorCatalog, errorHelpInfo = catalog, helpInfo
+	logInfo("Error messages loaded, languages: %v, default: %s", catalog.Languages(), catalog.DefaultLanguage())
+	return nil
+}
+
+// localizeError 按 Accept-Language 返回本地化的错误信息副本及所用语言
+func localizeError(c *app.RequestContext, errInfo *GHProxyErrors) (*GHProxyErrors, string) {
+	if errorCatalog == nil {
+		return errInfo, ""
+	}
+	lang := errorCatalog.Match(string(c.Request.Header.Peek("Accept-Language")))
+	localized := *errInfo
+	code := errInfo.Code
+	if code == "" {
+		code = statusErrorCode(errInfo.StatusCode)
+	}
+	if msg, ok := errorCatalog.Lookup(lang, code); ok {
+		if msg.StatusText != "" {
+			localized.StatusText = msg.StatusText
+		}
+		if msg.HelpInfo != "" {
+			localized.HelpInfo = msg.HelpInfo
+		}
+	}
+	if help, ok := errorHelpInfo[errInfo.StatusCode]; ok {
+		localized.HelpInfo = help
+	}
+	return &localized, lang
+}
@@ -29,49 +29,76 @@ func HandleError(c *app.RequestContext, message string) {
 
 type GHProxyErrors struct {
 	StatusCode   int
+	Code         string // 稳定的错误码, 用于 JSON 等非 HTML 响应
 	StatusDesc   string
 	StatusText   string
 	HelpInfo     string
 	ErrorMessage string
 }
 
+// WithCode 设置更具体的错误码
+func (e *GHProxyErrors) WithCode(code string) *GHProxyErrors {
+	e.Code = code
+	return e
+}
+
 var (
 	ErrInvalidURL = &GHProxyErrors{
 		StatusCode: 400,
+		Code:       "INVALID_URL",
 		StatusDesc: "Bad Request",
 		StatusText: "无效请求",
 		HelpInfo:   "请求的URL格式不正确，请检查后重试。",
 	}
 	ErrAuthHeaderUnavailable = &GHProxyErrors{
 		StatusCode: 401,
+		Code:       "AUTH_REQUIRED",
 		StatusDesc: "Unauthorized",
 		StatusText: "认证失败",
 		HelpInfo:   "缺少或无效的鉴权信息。",
 	}
 	ErrForbidden = &GHProxyErrors{
 		StatusCode: 403,
+		Code:       "FORBIDDEN",
 		StatusDesc: "Forbidden",
 		StatusText: "权限不足",
 		HelpInfo:   "您没有权限访问此资源。",
 	}
 	ErrNotFound = &GHProxyErrors{
 		StatusCode: 404,
+		Code:       "NOT_FOUND",
 		StatusDesc: "Not Found",
 		StatusText: "页面未找到",
 		HelpInfo:   "抱歉，您访问的页面不存在。",
 	}
+	ErrPayloadTooLarge = &GHProxyErrors{
+		StatusCode: 413,
+		Code:       "SIZE_LIMIT",
+		StatusDesc: "Payload Too Large",
+		StatusText: "文件过大",
+		HelpInfo:   "请求的文件超出了本代理允许的大小限制，请直接从源站下载或联系管理员。",
+	}
 	ErrTooManyRequests = &GHProxyErrors{
 		StatusCode: 429,
+		Code:       "RATE_LIMITED",
 		StatusDesc: "Too Many Requests",
 		StatusText: "请求过于频繁",
 		HelpInfo:   "您的请求过于频繁，请稍后再试。",
 	}
 	ErrInternalServerError = &GHProxyErrors{
 		StatusCode: 500,
+		Code:       "INTERNAL_ERROR",
 		StatusDesc: "Internal Server Error",
 		StatusText: "服务器内部错误",
 		HelpInfo:   "服务器处理您的请求时发生错误，请稍后重试或联系管理员。",
 	}
+	ErrServiceUnavailable = &GHProxyErrors{
+		StatusCode: 503,
+		Code:       "UPSTREAM_UNAVAILABLE",
+		StatusDesc: "Service Unavailable",
+		StatusText: "上游服务暂不可用",
+		HelpInfo:   "上游服务器连续请求失败，已暂时停止转发，请稍后重试。",
+	}
 )
 
 var statusErrorMap map[int]*GHProxyErrors
@@ -82,8 +109,10 @@ func init() {
 		ErrAuthHeaderUnavailable.StatusCode: ErrAuthHeaderUnavailable,
 		ErrForbidden.StatusCode:             ErrForbidden,
 		ErrNotFound.StatusCode:              ErrNotFound,
+		ErrPayloadTooLarge.StatusCode:       ErrPayloadTooLarge,
 		ErrTooManyRequests.StatusCode:       ErrTooManyRequests,
 		ErrInternalServerError.StatusCode:   ErrInternalServerError,
+		ErrServiceUnavailable.StatusCode:    ErrServiceUnavailable,
 	}
 }
 
@@ -93,6 +122,7 @@ func NewErrorWithStatusLookup(statusCode int, errMsg string) *GHProxyErrors {
 	if found {
 		return &GHProxyErrors{
 			StatusCode:   baseErr.StatusCode,
+			Code:         baseErr.Code,
 			StatusDesc:   baseErr.StatusDesc,
 			StatusText:   baseErr.StatusText,
 			HelpInfo:     baseErr.HelpInfo,
@@ -101,6 +131,7 @@ func NewErrorWithStatusLookup(statusCode int, errMsg string) *GHProxyErrors {
 	} else {
 		return &GHProxyErrors{
 			StatusCode:   statusCode,
+			Code:         statusErrorCode(statusCode),
 			ErrorMessage: errMsg,
 		}
 	}
@@ -162,6 +193,17 @@ func ErrPageUnwarper(errInfo *GHProxyErrors) ErrorPageData {
 }
 
 func ErrorPage(c *app.RequestContext, errInfo *GHProxyErrors) {
+	errInfo, lang := localizeError(c, errInfo)
+	if lang != "" {
+		c.Header("Content-Language", lang)
+	}
+
+	// 非浏览器客户端按其可解析的格式返回
+	if format := negotiateErrorFormat(c); format != errorFormatHTML {
+		writeErrorFormat(c, errInfo, format)
+		return
+	}
+
 	// 如果是404错误且有自定义404页面，则使用自定义页面
 	if errInfo.StatusCode == 404 && hasCustom404 {
 		pageData, err := os.ReadFile(custom404Path)
@@ -174,9 +216,10 @@ func ErrorPage(c *app.RequestContext, errInfo *GHProxyErrors) {
 	}
 
 	// 使用内置错误页面模板
-	pageData, err := htmlTemplateRender(errPagesFs, ErrPageUnwarper(errInfo))
+	data := ErrPageUnwarper(errInfo)
+	pageData, err := htmlTemplateRender(errPagesFs, data)
 	if err != nil {
-		c.JSON(errInfo.StatusCode, map[string]string{"error": errInfo.ErrorMessage})
+		writeErrorFormat(c, errInfo, errorFormatJSON)
 		logDebug("Error reading page.tmpl: %v", err)
 		return
 	}
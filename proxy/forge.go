@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"strings"
+)
+
+// 支持的代码托管平台
+const (
+	forgeGitlab    = "gitlab"
+	forgeGitea     = "gitea"   // Gitea 与 Codeberg
+	forgeForgejo   = "forgejo" // 路径与 Gitea 一致
+	forgeBitbucket = "bitbucket"
+)
+
+var forgeParsers = map[string]pathParser{
+	forgeGitlab:    parseGitlab,
+	forgeGitea:     parseGitea,
+	forgeForgejo:   parseGitea,
+	forgeBitbucket: parseBitbucket,
+}
+
+// forgeUpstream 以平台对应的路径解析构建上游, git 克隆请求交由 GitReq 处理
+func forgeUpstream(name string, forge string, hosts []string, shell bool) (*upstreamKind, bool) {
+	parse, ok := forgeParsers[forge]
+	if !ok {
+		return nil, false
+	}
+	kind := &upstreamKind{
+		name:    name,
+		hosts:   hosts,
+		parse:   parse,
+		clients: map[string]string{"clone": upstreamClientGit},
+		rewrite: shell,
+	}
+	if shell {
+		kind.edit = map[string]struct{}{"blob": {}, "raw": {}}
+	}
+	return kind, true
+}
+
+// parseGitPath 解析 git smart http 请求 .../repo.git/info/refs 与 .../repo.git/git-upload-pack
+// 不带 .git 后缀时仅接受 user/repo/info/refs 形式, 以免与仓库内同名文件混淆; 嵌套命名空间时 user 为完整的命名空间路径
+func parseGitPath(parts []string) (string, string, bool) {
+	n := len(parts)
+	var repoIdx int
+	switch {
+	case n >= 4 && parts[n-2] == "info" && parts[n-1] == "refs":
+		repoIdx = n - 3
+	case n >= 3 && parts[n-1] == "git-upload-pack":
+		repoIdx = n - 2
+	default:
+		return "", "", false
+	}
+	if repoIdx < 1 || (repoIdx > 1 && !strings.HasSuffix(parts[repoIdx], ".git")) {
+		return "", "", false
+	}
+	return strings.Join(parts[:repoIdx], "/"), strings.TrimSuffix(parts[repoIdx], ".git"), true
+}
+
+// parseGitlab 解析 gitlab.com/group[/subgroup...]/project/-/{raw,blob,releases,archive}/...
+// 嵌套分组时 user 取完整的分组路径(如 group/subgroup), repo 取项目名, 名单按命名空间逐级匹配
+func parseGitlab(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if user, repo, ok := parseGitPath(parts); ok {
+		return user, repo, "clone", nil
+	}
+	sep := -1
+	for i, part := range parts {
+		if part == "-" {
+			sep = i
+			break
+		}
+	}
+	if sep < 2 || sep+2 >= len(parts) {
+		errMsg := "URL after matched GitLab host should be group/project/-/type/..."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	var matcher string
+	switch parts[sep+1] {
+	case "raw":
+		matcher = "raw"
+	case "blob":
+		matcher = "blob"
+	case "releases", "archive":
+		matcher = "releases"
+	default:
+		errMsg := "Url Matched GitLab host, but didn't match raw, blob, releases or archive"
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return strings.Join(parts[:sep-1], "/"), parts[sep-1], matcher, nil
+}
+
+// parseGitea 解析 Gitea/Forgejo 的 user/repo/{raw,media}/branch|tag|commit/..., user/repo/releases/download/... 与 user/repo/archive/...
+func parseGitea(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if user, repo, ok := parseGitPath(parts); ok {
+		return user, repo, "clone", nil
+	}
+	if len(parts) < 4 {
+		errMsg := "URL after matched Gitea host should have at least 4 parts (user/repo/type/...)."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	var matcher string
+	switch parts[2] {
+	case "raw", "media":
+		matcher = "raw"
+	case "releases", "archive":
+		matcher = "releases"
+	default:
+		errMsg := "Url Matched Gitea host, but didn't match raw, media, releases or archive"
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return parts[0], parts[1], matcher, nil
+}
+
+// parseBitbucket 解析 bitbucket.org/user/repo/{downloads,get,raw}/...
+func parseBitbucket(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if user, repo, ok := parseGitPath(parts); ok {
+		return user, repo, "clone", nil
+	}
+	if len(parts) < 4 {
+		errMsg := "URL after matched Bitbucket host should have at least 4 parts (user/repo/type/...)."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	var matcher string
+	switch parts[2] {
+	case "raw":
+		matcher = "raw"
+	case "downloads", "get":
+		matcher = "releases"
+	default:
+		errMsg := "Url Matched Bitbucket host, but didn't match raw, downloads or get"
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return parts[0], parts[1], matcher, nil
+}
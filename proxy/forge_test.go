@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"ghproxy/auth"
+	"ghproxy/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		user string
+		repo string
+		ok   bool
+	}{
+		{"octo/hello.git/info/refs", "octo", "hello", true},
+		{"octo/hello/info/refs", "octo", "hello", true},
+		{"octo/hello.git/git-upload-pack", "octo", "hello", true},
+		{"octo/hello/git-upload-pack", "octo", "hello", true},
+		{"group/sub/project.git/info/refs", "group/sub", "project", true},
+		{"group/sub/project/info/refs", "", "", false},
+		{"octo/info/refs", "", "", false},
+		{"octo/hello/raw/main/info/refs", "", "", false},
+		{"octo/hello/raw/main/file", "", "", false},
+	}
+	for _, tt := range tests {
+		user, repo, ok := parseGitPath(strings.Split(tt.path, "/"))
+		if user != tt.user || repo != tt.repo || ok != tt.ok {
+			t.Errorf("parseGitPath(%q) = %q, %q, %v; want %q, %q, %v", tt.path, user, repo, ok, tt.user, tt.repo, tt.ok)
+		}
+	}
+}
+
+func TestForgeParsers(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   pathParser
+		path    string
+		user    string
+		repo    string
+		matcher string // 为空表示应返回 400
+	}{
+		{"gitlab raw", parseGitlab, "octo/hello/-/raw/main/install.sh", "octo", "hello", "raw"},
+		{"gitlab blob", parseGitlab, "octo/hello/-/blob/main/README.md", "octo", "hello", "blob"},
+		{"gitlab release", parseGitlab, "octo/hello/-/releases/v1/downloads/a.tar.gz", "octo", "hello", "releases"},
+		{"gitlab archive", parseGitlab, "octo/hello/-/archive/main/hello-main.zip", "octo", "hello", "releases"},
+		{"gitlab nested group", parseGitlab, "group/sub/project/-/raw/main/f", "group/sub", "project", "raw"},
+		{"gitlab deeply nested group", parseGitlab, "a/b/c/project/-/releases/v1/downloads/x", "a/b/c", "project", "releases"},
+		{"gitlab clone", parseGitlab, "group/sub/project.git/info/refs", "group/sub", "project", "clone"},
+		{"gitlab unknown type", parseGitlab, "octo/hello/-/issues/1", "", "", ""},
+		{"gitlab missing separator", parseGitlab, "octo/hello/raw/main/f", "", "", ""},
+		{"gitlab separator too early", parseGitlab, "octo/-/raw/main", "", "", ""},
+		{"gitea raw", parseGitea, "octo/hello/raw/branch/main/f", "octo", "hello", "raw"},
+		{"gitea media", parseGitea, "octo/hello/media/branch/main/f.png", "octo", "hello", "raw"},
+		{"gitea release", parseGitea, "octo/hello/releases/download/v1/a.tar.gz", "octo", "hello", "releases"},
+		{"gitea archive", parseGitea, "octo/hello/archive/main.tar.gz", "octo", "hello", "releases"},
+		{"gitea clone", parseGitea, "octo/hello.git/git-upload-pack", "octo", "hello", "clone"},
+		{"gitea too short", parseGitea, "octo/hello/raw", "", "", ""},
+		{"gitea unknown type", parseGitea, "octo/hello/issues/1", "", "", ""},
+		{"bitbucket raw", parseBitbucket, "octo/hello/raw/main/f", "octo", "hello", "raw"},
+		{"bitbucket downloads", parseBitbucket, "octo/hello/downloads/a.zip", "octo", "hello", "releases"},
+		{"bitbucket get", parseBitbucket, "octo/hello/get/main.zip", "octo", "hello", "releases"},
+		{"bitbucket clone", parseBitbucket, "octo/hello/info/refs", "octo", "hello", "clone"},
+		{"bitbucket unknown type", parseBitbucket, "octo/hello/src/main/f", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, repo, matcher, err := tt.parse(strings.Split(tt.path, "/"), &config.Config{})
+			if tt.matcher == "" {
+				if err == nil || err.StatusCode != 400 {
+					t.Fatalf("parse(%q) = %s, %s, %s, %v; want 400", tt.path, user, repo, matcher, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse(%q) err = %v", tt.path, err.ErrorMessage)
+			}
+			if user != tt.user || repo != tt.repo || matcher != tt.matcher {
+				t.Fatalf("parse(%q) = %s, %s, %s; want %s, %s, %s", tt.path, user, repo, matcher, tt.user, tt.repo, tt.matcher)
+			}
+		})
+	}
+}
+
+func TestGitlabSubgroupLists(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.Whitelist.WhitelistFile = filepath.Join(dir, "whitelist.json")
+	cfg.Blacklist.BlacklistFile = filepath.Join(dir, "blacklist.json")
+	os.WriteFile(cfg.Whitelist.WhitelistFile, []byte(`{"whitelist":["group/sub","team/*","solo/tool"]}`), 0644)
+	os.WriteFile(cfg.Blacklist.BlacklistFile, []byte(`{"blacklist":["group/sub/secret","group/project"]}`), 0644)
+	if err := auth.InitWhitelist(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.InitBlacklist(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path        string
+		whitelisted bool
+		blacklisted bool
+	}{
+		{"group/sub/project/-/raw/main/f", true, false},
+		{"group/sub/inner/project/-/raw/main/f", true, false},
+		{"group/sub/secret/-/raw/main/f", true, true},
+		{"group/other/project/-/raw/main/f", false, false},
+		{"group/project/-/raw/main/f", false, true},
+		{"group/project/nested/-/raw/main/f", false, true},
+		{"group/other-sub/project/-/raw/main/f", false, false},
+		{"team/a/b/-/blob/main/f", true, false},
+		{"solo/tool/-/raw/main/f", true, false},
+		{"solo/other/-/raw/main/f", false, false},
+		{"group/sub/project.git/info/refs", true, false},
+	}
+	for _, tt := range tests {
+		user, repo, _, err := parseGitlab(strings.Split(tt.path, "/"), cfg)
+		if err != nil {
+			t.Fatalf("parseGitlab(%q) err = %v", tt.path, err.ErrorMessage)
+		}
+		if got := auth.CheckWhitelist(user, repo); got != tt.whitelisted {
+			t.Errorf("%s: CheckWhitelist(%q, %q) = %v, want %v", tt.path, user, repo, got, tt.whitelisted)
+		}
+		if got := auth.CheckBlacklist(user, repo); got != tt.blacklisted {
+			t.Errorf("%s: CheckBlacklist(%q, %q) = %v, want %v", tt.path, user, repo, got, tt.blacklisted)
+		}
+	}
+}
@@ -7,8 +7,8 @@ import (
 	"ghproxy/config"
 	"net/http"
 	"strconv"
+	"strings"
 
-	"github.com/WJQSERVER-STUDIO/go-utils/limitreader"
 	"github.com/cloudwego/hertz/pkg/app"
 )
 
@@ -19,6 +19,8 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 		resp *http.Response
 	)
 
+	ctx = withOutboundRoute(ctx, "clone")
+
 	go func() {
 		<-ctx.Done()
 		if resp != nil && resp.Body != nil {
@@ -35,7 +37,10 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 
 	//bodyReader := c.Request.BodyStream() // 不可替换为此实现
 
-	if cfg.GitClone.Mode == "cache" {
+	// 智能 Git 服务仅缓存 GitHub 仓库, 其他平台的克隆直接转发
+	cache := cfg.GitClone.Mode == "cache" && strings.HasPrefix(u, "https://github.com/")
+
+	if cache {
 		userPath, repoPath, remainingPath, queryParams, err := extractParts(u)
 		if err != nil {
 			HandleError(c, fmt.Sprintf("Failed to extract parts from URL: %v", err))
@@ -45,8 +50,9 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 		u = cfg.GitClone.SmartGitAddr + userPath + repoPath + remainingPath + "?" + queryParams.Encode()
 	}
 
-	if cfg.GitClone.Mode == "cache" {
-		rb := gitclient.NewRequestBuilder(method, u)
+	if cache {
+		cl := clientFor(gitclient, c)
+		rb := cl.NewRequestBuilder(method, u)
 		rb.NoDefaultHeaders()
 		rb.SetBody(reqBodyReader)
 		rb.WithContext(ctx)
@@ -59,13 +65,14 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 		setRequestHeaders(c, req, cfg, "clone")
 		AuthPassThrough(c, cfg, req)
 
-		resp, err = gitclient.Do(req)
+		resp, err = upstreamDo(cl, req, cfg)
 		if err != nil {
-			HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+			HandleUpstreamError(c, u, err)
 			return
 		}
 	} else {
-		rb := client.NewRequestBuilder(string(c.Request.Method()), u)
+		cl := clientFor(client, c)
+		rb := cl.NewRequestBuilder(string(c.Request.Method()), u)
 		rb.NoDefaultHeaders()
 		rb.SetBody(reqBodyReader)
 		rb.WithContext(ctx)
@@ -78,24 +85,24 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 		setRequestHeaders(c, req, cfg, "clone")
 		AuthPassThrough(c, cfg, req)
 
-		resp, err = client.Do(req)
+		resp, err = upstreamDo(cl, req, cfg)
 		if err != nil {
-			HandleError(c, fmt.Sprintf("Failed to send request: %v", err))
+			HandleUpstreamError(c, u, err)
 			return
 		}
 	}
 
+	sizelimit := cfg.Server.SizeLimit * 1024 * 1024
+	sizeKnown := false
 	contentLength := resp.Header.Get("Content-Length")
 	if contentLength != "" {
 		size, err := strconv.Atoi(contentLength)
-		sizelimit := cfg.Server.SizeLimit * 1024 * 1024
 		if err != nil {
 			logWarning("%s %s %s %s %s Content-Length header is not a valid integer: %v", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), err)
 		}
-		if err == nil && size > sizelimit {
-			finalURL := []byte(resp.Request.URL.String())
-			c.Redirect(http.StatusMovedPermanently, finalURL)
-			logWarning("%s %s %s %s %s Final-URL: %s Size-Limit-Exceeded: %d", c.ClientIP(), c.Method(), c.Path(), c.Request.Header.Get("User-Agent"), c.Request.Header.GetProtocol(), finalURL, size)
+		sizeKnown = err == nil
+		if err == nil && size > sizelimit && !sizeLimitExempt(c, cfg, "clone") {
+			handleOverLimit(c, cfg, resp, "clone", size)
 			return
 		}
 	}
@@ -116,19 +123,11 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 		resp.Header.Del(header)
 	}
 
-	switch cfg.Server.Cors {
-	case "*":
-		c.Header("Access-Control-Allow-Origin", "*")
-	case "":
-		c.Header("Access-Control-Allow-Origin", "*")
-	case "nil":
-		c.Header("Access-Control-Allow-Origin", "")
-	default:
-		c.Header("Access-Control-Allow-Origin", cfg.Server.Cors)
-	}
+	setCorsHeader(c, cfg)
+	applyResponseHeaderRules(c, u, "clone")
 
 	c.Status(resp.StatusCode)
-	if cfg.GitClone.Mode == "cache" {
+	if cache {
 		c.Response.Header.Set("Cache-Control", "no-store, no-cache, must-revalidate")
 		c.Response.Header.Set("Pragma", "no-cache")
 		c.Response.Header.Set("Expires", "0")
@@ -136,9 +135,11 @@ func GitReq(ctx context.Context, c *app.RequestContext, u string, cfg *config.Co
 
 	bodyReader := resp.Body
 
-	if cfg.RateLimit.BandwidthLimit.Enabled {
-		bodyReader = limitreader.NewRateLimitedReader(bodyReader, bandwidthLimit, int(bandwidthBurst), ctx)
+	// 未声明长度的响应边读边计数
+	if !sizeKnown && !sizeLimitExempt(c, cfg, "clone") {
+		bodyReader = newLimitedBody(bodyReader, sizelimit, u)
 	}
 
+	bodyReader = limitBandwidth(ctx, bodyReader)
 	c.SetBodyStream(bodyReader, -1)
 }
@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"ghproxy/ban"
 	"ghproxy/config"
 	"ghproxy/rate"
 	"regexp"
@@ -17,6 +18,11 @@ func NoRouteHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 	return func(ctx context.Context, c *app.RequestContext) {
 
 		var shoudBreak bool
+		shoudBreak = banCheck(cfg, c)
+		if shoudBreak {
+			return
+		}
+
 		shoudBreak = rateCheck(cfg, c, limiter, iplimiter)
 		if shoudBreak {
 			return
@@ -34,6 +40,7 @@ func NoRouteHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 		if len(matches) < 3 {
 			logWarning("%s %s %s %s %s Invalid URL", c.ClientIP(), c.Method(), c.Path(), c.Request.Header.UserAgent(), c.Request.Header.GetProtocol())
 			ErrorPage(c, NewErrorWithStatusLookup(400, fmt.Sprintf("Invalid URL Format: %s", c.Path())))
+			ban.Record(c.ClientIP(), ban.SignalInvalidURL)
 			return
 		}
 
@@ -50,6 +57,9 @@ func NoRouteHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 		user, repo, matcher, matcherErr = Matcher(rawPath, cfg)
 		if matcherErr != nil {
 			ErrorPage(c, matcherErr)
+			if matcherErr.StatusCode == 400 {
+				ban.Record(c.ClientIP(), ban.SignalInvalidURL)
+			}
 			return
 		}
 
@@ -66,6 +76,11 @@ func NoRouteHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 			return
 		}
 
+		shoudBreak = apiMethodCheck(c, cfg, matcher, rawPath)
+		if shoudBreak {
+			return
+		}
+
 		// 处理blob/raw路径
 		if matcher == "blob" {
 			rawPath = strings.Replace(rawPath, "/blob/", "/raw/", 1)
@@ -73,15 +88,6 @@ func NoRouteHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 
 		logDebug("Matched: %v", matcher)
 
-		switch matcher {
-		case "releases", "blob", "raw", "gist", "api":
-			ChunkedProxyRequest(ctx, c, rawPath, cfg, matcher)
-		case "clone":
-			GitReq(ctx, c, rawPath, cfg, "git")
-		default:
-			ErrorPage(c, NewErrorWithStatusLookup(500, "Matched But Not Matched"))
-			logError("Matched But Not Matched Path: %s rawPath: %s matcher: %s", c.Path(), rawPath, matcher)
-			return
-		}
+		dispatch(ctx, c, rawPath, cfg, matcher)
 	}
 }
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"ghproxy/config"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol"
+)
+
+// 规则作用方向
+const (
+	headerDirectionRequest  = "request"  // 发往上游的请求头
+	headerDirectionResponse = "response" // 返回客户端的响应头
+)
+
+// headerRule 预处理后的header改写规则
+type headerRule struct {
+	matchers  map[string]struct{} // 为空表示作用于所有matcher
+	direction string
+	remove    []string
+	rename    [][2]string
+	set       [][2]string
+	add       [][2]string
+}
+
+var (
+	requestHeaderRules  []*headerRule
+	responseHeaderRules []*headerRule
+)
+
+// headerEditor 统一 http.Header 与 hertz ResponseHeader 的改写操作
+type headerEditor interface {
+	Values(key string) []string
+	Del(key string)
+	Set(key, value string)
+	Add(key, value string)
+}
+
+// hertzHeaderEditor 适配 hertz 的响应头
+type hertzHeaderEditor struct {
+	h *protocol.ResponseHeader
+}
+
+func (e hertzHeaderEditor) Values(key string) []string {
+	raw := e.h.PeekAll(key)
+	values := make([]string, 0, len(raw))
+	for _, v := range raw {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+func (e hertzHeaderEditor) Del(key string)        { e.h.Del(key) }
+func (e hertzHeaderEditor) Set(key, value string) { e.h.Set(key, value) }
+func (e hertzHeaderEditor) Add(key, value string) { e.h.Add(key, value) }
+
+// InitHeaderRules 解析header改写规则
+func InitHeaderRules(cfg *config.Config) error {
+	requestHeaderRules = nil
+	responseHeaderRules = nil
+	for i, r := range cfg.Headers.Rules {
+		rule := &headerRule{
+			direction: strings.ToLower(r.Direction),
+		}
+		if len(r.Matchers) > 0 {
+			rule.matchers = make(map[string]struct{}, len(r.Matchers))
+			for _, m := range r.Matchers {
+				rule.matchers[m] = struct{}{}
+			}
+		}
+		for _, key := range r.Remove {
+			rule.remove = append(rule.remove, http.CanonicalHeaderKey(key))
+		}
+		for _, kv := range sortedHeaderPairs(r.Rename) {
+			rule.rename = append(rule.rename, [2]string{kv[0], http.CanonicalHeaderKey(kv[1])})
+		}
+		rule.set = sortedHeaderPairs(r.Set)
+		rule.add = sortedHeaderPairs(r.Add)
+
+		switch rule.direction {
+		case headerDirectionRequest:
+			requestHeaderRules = append(requestHeaderRules, rule)
+		case headerDirectionResponse:
+			responseHeaderRules = append(responseHeaderRules, rule)
+		default:
+			return fmt.Errorf("headers.rules[%d]: unknown direction %q, must be request or response", i, r.Direction)
+		}
+	}
+	if len(requestHeaderRules)+len(responseHeaderRules) > 0 {
+		logInfo("Header rewrite rules loaded, request: %d, response: %d", len(requestHeaderRules), len(responseHeaderRules))
+	}
+	return nil
+}
+
+// sortedHeaderPairs 将配置中的映射按规范化后的header名排序, 使同一规则内的执行顺序固定
+func sortedHeaderPairs(m map[string]string) [][2]string {
+	pairs := make([][2]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, [2]string{http.CanonicalHeaderKey(key), value})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
+
+// applyRequestHeaderRules 改写发往上游的请求头
+func applyRequestHeaderRules(c *app.RequestContext, header http.Header, u string, matcher string) {
+	if len(requestHeaderRules) == 0 {
+		return
+	}
+	applyHeaderRules(requestHeaderRules, header, newHeaderTemplate(c, u, matcher), matcher)
+}
+
+// applyResponseHeaderRules 改写返回客户端的响应头, 需在设置完其他响应头之后调用
+func applyResponseHeaderRules(c *app.RequestContext, u string, matcher string) {
+	if len(responseHeaderRules) == 0 {
+		return
+	}
+	applyHeaderRules(responseHeaderRules, hertzHeaderEditor{&c.Response.Header}, newHeaderTemplate(c, u, matcher), matcher)
+}
+
+// applyHeaderRules 按顺序执行规则, 单条规则内依次为 remove、rename、set、add, 各操作内按header名排序执行
+func applyHeaderRules(rules []*headerRule, header headerEditor, tmpl *strings.Replacer, matcher string) {
+	for _, rule := range rules {
+		if rule.matchers != nil {
+			if _, ok := rule.matchers[matcher]; !ok {
+				continue
+			}
+		}
+		for _, key := range rule.remove {
+			header.Del(key)
+		}
+		for _, kv := range rule.rename {
+			values := header.Values(kv[0])
+			if len(values) == 0 {
+				continue
+			}
+			header.Del(kv[0])
+			header.Del(kv[1])
+			for _, v := range values {
+				header.Add(kv[1], v)
+			}
+		}
+		for _, kv := range rule.set {
+			header.Set(kv[0], expandHeaderTemplate(tmpl, kv[1]))
+		}
+		for _, kv := range rule.add {
+			header.Add(kv[0], expandHeaderTemplate(tmpl, kv[1]))
+		}
+	}
+}
+
+// newHeaderTemplate 构建模板变量: ${client_ip} ${request_id} ${matcher} ${filename}
+func newHeaderTemplate(c *app.RequestContext, u string, matcher string) *strings.Replacer {
+	var filename string
+	if parsed, err := url.Parse(u); err == nil {
+		filename = path.Base(parsed.Path)
+	}
+	return strings.NewReplacer(
+		"${client_ip}", c.ClientIP(),
+		"${request_id}", requestID(c),
+		"${matcher}", matcher,
+		"${filename}", filename,
+	)
+}
+
+func expandHeaderTemplate(tmpl *strings.Replacer, value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return tmpl.Replace(value)
+}
+
+// requestID 返回本次请求的ID, 同一请求的请求头与响应头模板共享同一ID
+func requestID(c *app.RequestContext) string {
+	if v, ok := c.Get("request_id"); ok {
+		if id, ok := v.(string); ok {
+			return id
+		}
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	id := hex.EncodeToString(b[:])
+	c.Set("request_id", id)
+	return id
+}
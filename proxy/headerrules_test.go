@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeaderRulesOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.HeaderRule
+		in   http.Header
+		want http.Header
+	}{
+		{
+			name: "chained rename",
+			rule: config.HeaderRule{Rename: map[string]string{"x-b": "X-C", "X-A": "x-b"}},
+			in:   http.Header{"X-A": {"1"}},
+			want: http.Header{"X-C": {"1"}},
+		},
+		{
+			name: "rename onto existing",
+			rule: config.HeaderRule{Rename: map[string]string{"X-A": "X-B"}},
+			in:   http.Header{"X-A": {"1", "2"}, "X-B": {"old"}},
+			want: http.Header{"X-B": {"1", "2"}},
+		},
+		{
+			name: "set with duplicate keys",
+			rule: config.HeaderRule{Set: map[string]string{"x-a": "lower", "X-A": "upper"}},
+			in:   http.Header{},
+			want: http.Header{"X-A": {"upper"}},
+		},
+		{
+			name: "add in name order",
+			rule: config.HeaderRule{Add: map[string]string{"Via": "b", "via": "a"}},
+			in:   http.Header{"Via": {"origin"}},
+			want: http.Header{"Via": {"origin", "a", "b"}},
+		},
+		{
+			name: "remove before rename and set",
+			rule: config.HeaderRule{Remove: []string{"x-a"}, Rename: map[string]string{"X-A": "X-B"}, Set: map[string]string{"X-A": "new"}},
+			in:   http.Header{"X-A": {"1"}},
+			want: http.Header{"X-A": {"new"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rule.Direction = "request"
+			defer InitHeaderRules(&config.Config{})
+			// 映射的遍历顺序随机, 多次加载与执行的结果应一致
+			for i := 0; i < 20; i++ {
+				if err := InitHeaderRules(&config.Config{Headers: config.HeadersConfig{Rules: []config.HeaderRule{tt.rule}}}); err != nil {
+					t.Fatal(err)
+				}
+				header := tt.in.Clone()
+				applyHeaderRules(requestHeaderRules, header, strings.NewReplacer(), "raw")
+				if !reflect.DeepEqual(header, tt.want) {
+					t.Fatalf("headers = %v, want %v", header, tt.want)
+				}
+			}
+		})
+	}
+}
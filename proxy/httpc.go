@@ -21,15 +21,58 @@ var (
 )
 
 func InitReq(cfg *config.Config) error {
+	if err := InitMatchers(cfg); err != nil {
+		return err
+	}
+	bodyIdleTimeout = time.Duration(cfg.Httpc.Timeouts.IdleRead) * time.Second
+	if err := InitSourceAddrs(cfg); err != nil {
+		return err
+	}
+	InitResolver(cfg)
+	if err := InitOutboundPool(cfg); err != nil {
+		return err
+	}
+	if err := InitRedirect(cfg); err != nil {
+		return err
+	}
 	initHTTPClient(cfg)
 	if cfg.GitClone.Mode == "cache" {
 		initGitHTTPClient(cfg)
 	}
 	initGhcrHTTPClient(cfg)
+	if outboundPool != nil {
+		outboundPool.StartHealthCheck()
+	}
+	InitBreaker(cfg)
+	if err := InitSegmented(cfg); err != nil {
+		return err
+	}
 	err := SetGlobalRateLimit(cfg)
 	if err != nil {
 		return err
 	}
+	_, err = InitDiskCache(cfg)
+	if err != nil {
+		return err
+	}
+	InitCoalesce(cfg)
+	InitAPICache(cfg)
+	if err := InitTokenPool(cfg); err != nil {
+		return err
+	}
+	InitAPIRewrite(cfg)
+	if err := InitHeaderRules(cfg); err != nil {
+		return err
+	}
+	if err := InitContentPolicy(cfg); err != nil {
+		return err
+	}
+	if err := InitChecksum(cfg); err != nil {
+		return err
+	}
+	if err := InitErrorMessages(cfg); err != nil {
+		return err
+	}
 	return nil
 
 }
@@ -71,16 +114,31 @@ func initHTTPClient(cfg *config.Config) {
 	if cfg.Outbound.Enabled {
 		initTransport(cfg, tr)
 	}
+	applyDialers(cfg, tr)
+	applyRedirectPolicy(tr)
+	client = newProxyClient(cfg, tr)
+	buildSourceClients(client, tr, func(t *http.Transport) *httpc.Client {
+		return newProxyClient(cfg, t)
+	})
+}
+
+func newProxyClient(cfg *config.Config, t *http.Transport) *httpc.Client {
+	var cl *httpc.Client
 	if cfg.Server.Debug {
-		client = httpc.New(
-			httpc.WithTransport(tr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithDumpLog(),
 		)
 	} else {
-		client = httpc.New(
-			httpc.WithTransport(tr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 		)
 	}
+	if cfg.Httpc.Retry.Enabled {
+		// 由 upstreamDo 接管重试, 避免与httpc内置重试叠加
+		cl.SetRetryOptions(httpc.RetryOptions{MaxAttempts: 0})
+	}
+	return cl
 }
 
 func initGitHTTPClient(cfg *config.Config) {
@@ -115,24 +173,33 @@ func initGitHTTPClient(cfg *config.Config) {
 	if cfg.Outbound.Enabled {
 		initTransport(cfg, gittr)
 	}
+	applyDialers(cfg, gittr)
+	gitclient = newGitClient(cfg, gittr)
+	buildSourceClients(gitclient, gittr, func(t *http.Transport) *httpc.Client {
+		return newGitClient(cfg, t)
+	})
+}
+
+func newGitClient(cfg *config.Config, t *http.Transport) *httpc.Client {
+	var cl *httpc.Client
 	if cfg.Server.Debug && cfg.GitClone.ForceH2C {
-		gitclient = httpc.New(
-			httpc.WithTransport(gittr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithDumpLog(),
 			httpc.WithProtocols(httpc.ProtocolsConfig{
 				ForceH2C: true,
 			}),
 		)
 	} else if !cfg.Server.Debug && cfg.GitClone.ForceH2C {
-		gitclient = httpc.New(
-			httpc.WithTransport(gittr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithProtocols(httpc.ProtocolsConfig{
 				ForceH2C: true,
 			}),
 		)
 	} else if cfg.Server.Debug && !cfg.GitClone.ForceH2C {
-		gitclient = httpc.New(
-			httpc.WithTransport(gittr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithDumpLog(),
 			httpc.WithProtocols(httpc.ProtocolsConfig{
 				Http1:           true,
@@ -141,8 +208,8 @@ func initGitHTTPClient(cfg *config.Config) {
 			}),
 		)
 	} else {
-		gitclient = httpc.New(
-			httpc.WithTransport(gittr),
+		cl = httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithProtocols(httpc.ProtocolsConfig{
 				Http1:           true,
 				Http2:           true,
@@ -150,6 +217,10 @@ func initGitHTTPClient(cfg *config.Config) {
 			}),
 		)
 	}
+	if cfg.Httpc.Retry.Enabled {
+		cl.SetRetryOptions(httpc.RetryOptions{MaxAttempts: 0})
+	}
+	return cl
 }
 
 func initGhcrHTTPClient(cfg *config.Config) {
@@ -188,14 +259,21 @@ func initGhcrHTTPClient(cfg *config.Config) {
 	if cfg.Outbound.Enabled {
 		initTransport(cfg, ghcrtr)
 	}
+	applyDialers(cfg, ghcrtr)
+	ghcrclient = newGhcrClient(cfg, ghcrtr)
+	buildSourceClients(ghcrclient, ghcrtr, func(t *http.Transport) *httpc.Client {
+		return newGhcrClient(cfg, t)
+	})
+}
+
+func newGhcrClient(cfg *config.Config, t *http.Transport) *httpc.Client {
 	if cfg.Server.Debug {
-		ghcrclient = httpc.New(
-			httpc.WithTransport(ghcrtr),
+		return httpc.New(
+			httpc.WithTransport(t),
 			httpc.WithDumpLog(),
 		)
-	} else {
-		ghcrclient = httpc.New(
-			httpc.WithTransport(ghcrtr),
-		)
 	}
+	return httpc.New(
+		httpc.WithTransport(t),
+	)
 }
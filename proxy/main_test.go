@@ -0,0 +1,20 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "proxy-test")
+	if err != nil {
+		panic(err)
+	}
+	logger.Init(filepath.Join(dir, "test.log"), 1)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
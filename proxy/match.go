@@ -9,108 +9,114 @@ import (
 )
 
 func Matcher(rawPath string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
-	var (
-		user    string
-		repo    string
-		matcher string
-	)
-	// 匹配 "https://github.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://github.com") {
-		remainingPath := strings.TrimPrefix(rawPath, "https://github.com")
-		/*
-			if strings.HasPrefix(remainingPath, "/") {
-				remainingPath = strings.TrimPrefix(remainingPath, "/")
-			}
-		*/
-		remainingPath = strings.TrimPrefix(remainingPath, "/")
-		// 预期格式/user/repo/more...
-		// 取出user和repo和最后部分
-		parts := strings.Split(remainingPath, "/")
-		if len(parts) <= 2 {
-			errMsg := "Not enough parts in path after matching 'https://github.com*'"
-			return "", "", "", NewErrorWithStatusLookup(400, errMsg)
-		}
-		user = parts[0]
-		repo = parts[1]
-		// 匹配 "https://github.com"开头的链接
-		if len(parts) >= 3 {
-			switch parts[2] {
-			case "releases", "archive":
-				matcher = "releases"
-			case "blob":
-				matcher = "blob"
-			case "raw":
-				matcher = "raw"
-			case "info", "git-upload-pack":
-				matcher = "clone"
-			default:
-				errMsg := "Url Matched 'https://github.com*', but didn't match the next matcher"
-				return "", "", "", NewErrorWithStatusLookup(400, errMsg)
-			}
-		}
-		return user, repo, matcher, nil
+	kind, segs := lookupUpstream(rawPath)
+	if kind == nil {
+		//return "", "", "", ErrNotFound
+		errMsg := "Didn't match any matcher"
+		return "", "", "", NewErrorWithStatusLookup(404, errMsg)
+	}
+	return kind.parse(segs, cfg)
+}
+
+// parseGithub 解析 github.com/user/repo/...
+func parseGithub(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	// 预期格式/user/repo/more...
+	if len(parts) <= 2 {
+		errMsg := "Not enough parts in path after matching 'https://github.com*'"
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
 	}
-	// 匹配 "https://raw"开头的链接
-	if strings.HasPrefix(rawPath, "https://raw") {
-		remainingPath := strings.TrimPrefix(rawPath, "https://")
-		parts := strings.Split(remainingPath, "/")
-		if len(parts) <= 3 {
-			errMsg := "URL after matched 'https://raw*' should have at least 4 parts (user/repo/branch/file)."
+	var matcher string
+	switch parts[2] {
+	case "releases", "archive":
+		matcher = "releases"
+	case "blob":
+		matcher = "blob"
+	case "raw":
+		matcher = "raw"
+	case "info", "git-upload-pack":
+		matcher = "clone"
+	case "tarball", "zipball":
+		matcher = "archive"
+	case "commit", "pull", "compare":
+		// 仅代理 commit/PR/compare 的 .patch 与 .diff 视图, 不代理 HTML 页面
+		if len(parts) < 4 || !isPatchPath(parts[len(parts)-1]) {
+			errMsg := "Only .patch and .diff views of commits, pull requests and compares are supported"
 			return "", "", "", NewErrorWithStatusLookup(400, errMsg)
 		}
+		matcher = "patch"
+	default:
+		errMsg := "Url Matched 'https://github.com*', but didn't match the next matcher"
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return parts[0], parts[1], matcher, nil
+}
+
+// parseRaw 解析 raw.githubusercontent.com/user/repo/branch/file
+func parseRaw(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if len(parts) <= 2 {
+		errMsg := "URL after matched 'https://raw*' should have at least 4 parts (user/repo/branch/file)."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return parts[0], parts[1], "raw", nil
+}
+
+// parseGist 解析 gist.githubusercontent.com/user/gist_id/...
+func parseGist(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if len(parts) <= 2 {
+		errMsg := "URL after matched 'https://gist*' should have at least 4 parts (user/gist_id)."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
+	}
+	return parts[0], "", "gist", nil
+}
+
+// isPatchPath 判断路径最后一段是否为 .patch 或 .diff
+func isPatchPath(last string) bool {
+	return strings.HasSuffix(last, ".patch") || strings.HasSuffix(last, ".diff")
+}
+
+// parseAPI 解析 api.github.com/repos/user/repo/... 与 api.github.com/users/user/...
+// tarball/zipball 仅重定向到 codeload 源码归档, 按 archive 处理, 与其他 API 请求一样要求开启 API 代理
+func parseAPI(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	var user, repo string
+	if parts[0] == "repos" && len(parts) >= 3 {
 		user = parts[1]
 		repo = parts[2]
-		matcher = "raw"
-
-		return user, repo, matcher, nil
 	}
-	// 匹配 "https://gist"开头的链接
-	if strings.HasPrefix(rawPath, "https://gist") {
-		remainingPath := strings.TrimPrefix(rawPath, "https://")
-		parts := strings.Split(remainingPath, "/")
-		if len(parts) <= 3 {
-			errMsg := "URL after matched 'https://gist*' should have at least 4 parts (user/gist_id)."
-			return "", "", "", NewErrorWithStatusLookup(400, errMsg)
-		}
+	if parts[0] == "users" && len(parts) >= 2 {
 		user = parts[1]
-		repo = ""
-		matcher = "gist"
-		return user, repo, matcher, nil
 	}
-	// 匹配 "https://api.github.com/"开头的链接
-	if strings.HasPrefix(rawPath, "https://api.github.com/") {
-		matcher = "api"
-		remainingPath := strings.TrimPrefix(rawPath, "https://api.github.com/")
-
-		parts := strings.Split(remainingPath, "/")
-		if parts[0] == "repos" {
-			user = parts[1]
-			repo = parts[2]
-		}
-		if parts[0] == "users" {
-			user = parts[1]
+	if !cfg.Auth.ForceAllowApi {
+		if cfg.Auth.Method != "header" || !cfg.Auth.Enabled {
+			//return "", "", "", ErrAuthHeaderUnavailable
+			errMsg := "AuthHeader Unavailable, Need to open header auth to enable api proxy"
+			return "", "", "", NewErrorWithStatusLookup(403, errMsg).WithCode("AUTH_REQUIRED")
 		}
-		if !cfg.Auth.ForceAllowApi {
-			if cfg.Auth.Method != "header" || !cfg.Auth.Enabled {
-				//return "", "", "", ErrAuthHeaderUnavailable
-				errMsg := "AuthHeader Unavailable, Need to open header auth to enable api proxy"
-				return "", "", "", NewErrorWithStatusLookup(403, errMsg)
-			}
-		}
-		return user, repo, matcher, nil
 	}
-	//return "", "", "", ErrNotFound
-	errMsg := "Didn't match any matcher"
-	return "", "", "", NewErrorWithStatusLookup(404, errMsg)
+	if len(parts) >= 5 && parts[0] == "repos" && (parts[3] == "tarball" || parts[3] == "zipball") {
+		return parts[1], parts[2], "archive", nil
+	}
+	return user, repo, "api", nil
 }
 
-var (
-	matchedMatchers = []string{
-		"blob",
-		"raw",
-		"gist",
+// parseCodeload 解析源码归档链接 codeload.github.com/user/repo/format/ref (archive 的重定向目标)
+func parseCodeload(parts []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+	if len(parts) < 4 {
+		errMsg := "URL after matched 'https://codeload.github.com/' should have at least 4 parts (user/repo/format/ref)."
+		return "", "", "", NewErrorWithStatusLookup(400, errMsg)
 	}
-)
+	return parts[0], parts[1], "codeload", nil
+}
+
+// isDownloadMatcher 判断是否为文件下载类的匹配类型
+// 磁盘缓存, 合并请求, 分段下载与摘要校验仅作用于这些类型
+func isDownloadMatcher(matcher string) bool {
+	switch matcher {
+	case "releases", "archive", "codeload", "objects":
+		return true
+	default:
+		return false
+	}
+}
 
 // matchString 检查目标字符串是否在给定的字符串集合中
 func matchString(target string, stringsToMatch []string) bool {
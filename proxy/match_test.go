@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"strings"
+	"testing"
+)
+
+func TestParseAPI(t *testing.T) {
+	open := &config.Config{}
+	open.Auth.ForceAllowApi = true
+	closed := &config.Config{}
+
+	tests := []struct {
+		name    string
+		path    string
+		cfg     *config.Config
+		user    string
+		repo    string
+		matcher string
+		status  int
+	}{
+		{"repo api", "repos/octo/hello/issues", open, "octo", "hello", "api", 0},
+		{"user api", "users/octo", open, "octo", "", "api", 0},
+		{"tarball", "repos/octo/hello/tarball/main", open, "octo", "hello", "archive", 0},
+		{"zipball", "repos/octo/hello/zipball/v1.0", open, "octo", "hello", "archive", 0},
+		{"tarball without ref", "repos/octo/hello/tarball", open, "octo", "hello", "api", 0},
+		{"api gated", "repos/octo/hello/issues", closed, "", "", "", 403},
+		{"tarball gated", "repos/octo/hello/tarball/main", closed, "", "", "", 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, repo, matcher, err := parseAPI(strings.Split(tt.path, "/"), tt.cfg)
+			if tt.status != 0 {
+				if err == nil || err.StatusCode != tt.status {
+					t.Fatalf("parseAPI(%q) err = %v, want status %d", tt.path, err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAPI(%q) err = %v", tt.path, err.ErrorMessage)
+			}
+			if user != tt.user || repo != tt.repo || matcher != tt.matcher {
+				t.Fatalf("parseAPI(%q) = %s, %s, %s; want %s, %s, %s", tt.path, user, repo, matcher, tt.user, tt.repo, tt.matcher)
+			}
+		})
+	}
+}
@@ -13,34 +13,10 @@ import (
 	"strings"
 )
 
+// EditorMatcher 判断链接是否指向声明了 shell 链接改写的上游
 func EditorMatcher(rawPath string, cfg *config.Config) (bool, error) {
-	// 匹配 "https://github.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://github.com") {
-		return true, nil
-	}
-	// 匹配 "https://raw.githubusercontent.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://raw.githubusercontent.com") {
-		return true, nil
-	}
-	// 匹配 "https://raw.github.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://raw.github.com") {
-		return true, nil
-	}
-	// 匹配 "https://gist.githubusercontent.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://gist.githubusercontent.com") {
-		return true, nil
-	}
-	// 匹配 "https://gist.github.com"开头的链接
-	if strings.HasPrefix(rawPath, "https://gist.github.com") {
-		return true, nil
-	}
-	if cfg.Shell.RewriteAPI {
-		// 匹配 "https://api.github.com/"开头的链接
-		if strings.HasPrefix(rawPath, "https://api.github.com") {
-			return true, nil
-		}
-	}
-	return false, nil
+	kind, _ := lookupUpstream(rawPath)
+	return kind != nil && kind.rewrite, nil
 }
 
 // 匹配文件扩展名是sh的rawPath
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"ghproxy/config"
+	"ghproxy/outbound"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	outboundPool   *outbound.Pool
+	outboundRoutes map[string]*outboundRoute
+)
+
+// outboundRoute 按 matcher 指定的出站方式, direct 为 true 时直连, proxy 为 nil 时使用代理池
+type outboundRoute struct {
+	direct bool
+	proxy  *url.URL
+}
+
+type outboundRouteKey struct{}
+
+// InitOutboundPool 初始化出站代理池与按 matcher 的路由, 需在构建传输层之前调用
+func InitOutboundPool(cfg *config.Config) error {
+	if !cfg.Outbound.Enabled || len(cfg.Outbound.Pool) == 0 {
+		return nil
+	}
+	pool, err := outbound.New(outbound.Options{
+		Proxies:             cfg.Outbound.Pool,
+		Strategy:            cfg.Outbound.Strategy,
+		HealthCheckURL:      cfg.Outbound.HealthCheckUrl,
+		HealthCheckInterval: time.Duration(cfg.Outbound.HealthCheckInterval) * time.Second,
+		HealthCheckTimeout:  time.Duration(cfg.Outbound.HealthCheckTimeout) * time.Second,
+		MaxFails:            cfg.Outbound.MaxFails,
+	})
+	if err != nil {
+		return err
+	}
+
+	routes := make(map[string]*outboundRoute)
+	for matcher, target := range cfg.Outbound.Routes {
+		switch strings.ToLower(strings.TrimSpace(target)) {
+		case "", "pool":
+			continue
+		case "direct":
+			routes[matcher] = &outboundRoute{direct: true}
+		default:
+			u, err := outbound.ParseProxyURL(target)
+			if err != nil {
+				logWarning("Invalid outbound route %s = %q: %v, using pool", matcher, target, err)
+				continue
+			}
+			routes[matcher] = &outboundRoute{proxy: u}
+		}
+	}
+
+	outboundPool = pool
+	outboundRoutes = routes
+	return nil
+}
+
+// OutboundPoolStates 返回代理池状态
+func OutboundPoolStates() (bool, string, []outbound.MemberState) {
+	if outboundPool == nil {
+		return false, "", []outbound.MemberState{}
+	}
+	return true, outboundPool.Strategy(), outboundPool.States()
+}
+
+// withOutboundRoute 在请求上下文中记录 matcher, 供出站代理路由使用
+func withOutboundRoute(ctx context.Context, matcher string) context.Context {
+	if outboundPool == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, outboundRouteKey{}, matcher)
+}
+
+// outboundProxy 作为 http.Transport.Proxy, 按 matcher 路由或从代理池中选择代理
+func outboundProxy(req *http.Request) (*url.URL, error) {
+	if matcher, ok := req.Context().Value(outboundRouteKey{}).(string); ok {
+		if route, ok := outboundRoutes[matcher]; ok {
+			if route.direct {
+				return nil, nil
+			}
+			return route.proxy, nil
+		}
+	}
+	return outboundPool.Select(), nil
+}
+
+// applyOutboundPool 统计到各代理的连接数, 需在 applyResolver 之后调用以便按代理地址关联连接
+func applyOutboundPool(transport *http.Transport) {
+	if outboundPool == nil {
+		return
+	}
+	transport.DialContext = outboundPool.WrapDialer(transportDialContext(transport))
+}
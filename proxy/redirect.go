@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ghproxy/config"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// 重定向处理模式
+const (
+	redirectModeFollow  = "follow"  // 服务端跟随指向允许列表内主机的重定向
+	redirectModeRewrite = "rewrite" // 不跟随任何重定向, 将指向允许列表内主机的 Location 改写为经由本代理, 其他 Location 原样返回
+)
+
+var (
+	redirectMode         string // 为空表示未启用, 由 httpc 跟随全部重定向
+	redirectAllowedHosts map[string]struct{}
+	redirectFollowAssets bool // rewrite 模式下启用黑白名单时, 在服务端跟随指向CDN签名链接的重定向
+)
+
+var errRedirectStopped = errors.New("redirect stopped by policy")
+
+type redirectPolicyKey struct{}
+
+// redirectStop 记录被策略拦截的重定向响应
+type redirectStop struct {
+	cancel context.CancelFunc
+	resp   *http.Response
+}
+
+type redirectStopKey struct{}
+
+// InitRedirect 初始化重定向策略, 需在构建传输层之前调用
+func InitRedirect(cfg *config.Config) error {
+	redirectMode = ""
+	redirectAllowedHosts = nil
+	redirectFollowAssets = false
+	if !cfg.Redirect.Enabled {
+		return nil
+	}
+	mode := strings.ToLower(cfg.Redirect.Mode)
+	switch mode {
+	case redirectModeFollow, redirectModeRewrite:
+	default:
+		return fmt.Errorf("unknown redirect mode: %s", cfg.Redirect.Mode)
+	}
+	hosts := make(map[string]struct{}, len(cfg.Redirect.AllowedHosts))
+	for _, host := range cfg.Redirect.AllowedHosts {
+		hosts[strings.ToLower(strings.TrimSpace(host))] = struct{}{}
+	}
+	redirectMode = mode
+	redirectAllowedHosts = hosts
+	// 对CDN签名链接的直接请求无法按名单判断会被拒绝, 改写后客户端将无法下载
+	redirectFollowAssets = mode == redirectModeRewrite && (cfg.Whitelist.Enabled || cfg.Blacklist.Enabled) && !(cfg.Auth.ForceAllowApi && cfg.Auth.ForceAllowApiPassList)
+	logInfo("Redirect policy: %s, allowed hosts: %v", mode, cfg.Redirect.AllowedHosts)
+	return nil
+}
+
+func redirectHostAllowed(host string) bool {
+	_, ok := redirectAllowedHosts[strings.ToLower(host)]
+	return ok
+}
+
+// redirectFollowable 判断重定向是否在服务端跟随
+func redirectFollowable(u *url.URL) bool {
+	if !redirectHostAllowed(u.Hostname()) {
+		return false
+	}
+	return redirectMode == redirectModeFollow || redirectFollowAssets && isSignedAssetURL(u.String())
+}
+
+// withRedirectPolicy 标记请求需按重定向策略处理
+func withRedirectPolicy(ctx context.Context) context.Context {
+	if redirectMode == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, redirectPolicyKey{}, true)
+}
+
+// withRedirectStop 为带有重定向策略标记的请求准备拦截记录
+// httpc 会重试 Proxy 返回的错误, 拦截时取消请求上下文以跳出其重试循环
+// 未拦截时该上下文随请求上下文一同释放
+func withRedirectStop(req *http.Request) (*http.Request, *redirectStop) {
+	if _, ok := req.Context().Value(redirectPolicyKey{}).(bool); !ok {
+		return req, nil
+	}
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := &redirectStop{cancel: cancel}
+	return req.WithContext(context.WithValue(ctx, redirectStopKey{}, stop)), stop
+}
+
+// applyRedirectPolicy 在传输层选择代理前拦截不应跟随的重定向
+// http.Client 跟随重定向时新请求的 Response 字段为触发跳转的响应, 以此识别重定向请求
+// rewrite 模式下拦截全部重定向, 指向允许列表外主机的重定向同样不在服务端跟随; 启用黑白名单时指向CDN签名链接的重定向除外
+func applyRedirectPolicy(transport *http.Transport) {
+	if redirectMode == "" {
+		return
+	}
+	// 原传输层未设置 Proxy 时表示直连, 包装后保持直连
+	next := transport.Proxy
+	transport.Proxy = func(req *http.Request) (*url.URL, error) {
+		if req.Response != nil {
+			stop, ok := req.Context().Value(redirectStopKey{}).(*redirectStop)
+			if ok && !redirectFollowable(req.URL) {
+				stop.resp = req.Response
+				stop.cancel()
+				return nil, errRedirectStopped
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		return next(req)
+	}
+}
+
+// stoppedRedirect 返回被拦截的重定向响应, 其响应体已由 http.Client 关闭
+func (s *redirectStop) stoppedRedirect() *http.Response {
+	if s == nil || s.resp == nil {
+		return nil
+	}
+	resp := s.resp
+	resp.Body = http.NoBody
+	return resp
+}
+
+// rewriteLocation 将指向允许列表内主机的 Location 改写为经由本代理的地址
+func rewriteLocation(c *app.RequestContext, resp *http.Response) {
+	if redirectMode != redirectModeRewrite {
+		return
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return
+	}
+	target, err := url.Parse(location)
+	if err == nil && resp.Request != nil {
+		target = resp.Request.URL.ResolveReference(target)
+	}
+	if err != nil || target.Scheme != "https" || !redirectHostAllowed(target.Hostname()) {
+		return
+	}
+	proxied := "https://" + string(c.Request.Host()) + "/" + target.String()
+	c.Header("Location", proxied)
+	logDebug("%s %s %s Rewrite-Location: %s -> %s", c.ClientIP(), c.Method(), c.Path(), location, proxied)
+}
@@ -0,0 +1,326 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"ghproxy/config"
+	"ghproxy/rate"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+)
+
+// 上游请求的处理方式
+const (
+	upstreamClientProxy = "proxy" // ChunkedProxyRequest
+	upstreamClientGit   = "git"   // GitReq
+)
+
+// pathParser 从主机名之后的路径段中解析 user, repo 与 matcher
+type pathParser func(segs []string, cfg *config.Config) (string, string, string, *GHProxyErrors)
+
+// upstreamRoute 注册到 hertz 的快速路由, 未命中时由 NoRouteHandler 按注册表解析
+type upstreamRoute struct {
+	path    string // 主机名之后的路由, 如 "/:user/:repo/releases/*filepath"
+	matcher string
+}
+
+// upstreamKind 一类上游的匹配规则
+type upstreamKind struct {
+	name    string
+	hosts   []string
+	parse   pathParser
+	clients map[string]string   // matcher -> 处理方式, 未列出的为 upstreamClientProxy
+	rewrite bool                // shell 脚本中指向该上游的链接是否改写为经由本代理
+	edit    map[string]struct{} // 响应需进行 shell 链接改写的 matcher
+	routes  []upstreamRoute
+	methods []string // 快速路由接受的请求方法, 为空时仅 GET
+}
+
+func (k *upstreamKind) client(matcher string) string {
+	if client, ok := k.clients[matcher]; ok {
+		return client
+	}
+	return upstreamClientProxy
+}
+
+func (k *upstreamKind) editable(matcher string) bool {
+	_, ok := k.edit[matcher]
+	return ok
+}
+
+var (
+	upstreamKinds    []*upstreamKind
+	upstreamHosts    map[string]*upstreamKind // 主机名 -> 上游
+	upstreamWildcard []string                 // "*.example.com" 形式的主机名后缀, 如 ".example.com"
+)
+
+// builtinUpstreams 内置的 GitHub 及 GitLab/Codeberg/Bitbucket 上游
+func builtinUpstreams(cfg *config.Config) []*upstreamKind {
+	gitlab, _ := forgeUpstream("gitlab", forgeGitlab, []string{"gitlab.com"}, true)
+	codeberg, _ := forgeUpstream("codeberg", forgeGitea, []string{"codeberg.org"}, true)
+	bitbucket, _ := forgeUpstream("bitbucket", forgeBitbucket, []string{"bitbucket.org"}, true)
+	return []*upstreamKind{
+		{
+			name:    "github",
+			hosts:   []string{"github.com"},
+			parse:   parseGithub,
+			clients: map[string]string{"clone": upstreamClientGit},
+			rewrite: true,
+			edit:    map[string]struct{}{"blob": {}, "raw": {}},
+			routes: []upstreamRoute{
+				{"/:user/:repo/releases/*filepath", "releases"},
+				{"/:user/:repo/archive/*filepath", "releases"},
+				{"/:user/:repo/tarball/*filepath", "archive"},
+				{"/:user/:repo/zipball/*filepath", "archive"},
+				{"/:user/:repo/blob/*filepath", "blob"},
+				{"/:user/:repo/raw/*filepath", "raw"},
+				{"/:user/:repo/info/*filepath", "clone"},
+				{"/:user/:repo/git-upload-pack", "clone"},
+			},
+		},
+		{
+			name:    "raw",
+			hosts:   []string{"raw.githubusercontent.com", "raw.github.com"},
+			parse:   parseRaw,
+			rewrite: true,
+			edit:    map[string]struct{}{"raw": {}},
+			routes:  []upstreamRoute{{"/:user/:repo/*filepath", "raw"}},
+		},
+		{
+			name:    "gist",
+			hosts:   []string{"gist.githubusercontent.com", "gist.github.com"},
+			parse:   parseGist,
+			rewrite: true,
+			edit:    map[string]struct{}{"gist": {}},
+		},
+		{
+			name:    "api",
+			hosts:   []string{"api.github.com"},
+			parse:   parseAPI,
+			rewrite: cfg.Shell.RewriteAPI,
+			routes: []upstreamRoute{
+				{"/repos/:user/:repo/tarball/*filepath", "archive"},
+				{"/repos/:user/:repo/zipball/*filepath", "archive"},
+				{"/repos/:user/:repo/*filepath", "api"},
+				{"/graphql", "api"},
+			},
+			// 写操作由 [apiPolicy] 按方法授权
+			methods: []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete},
+		},
+		{
+			name:  "codeload",
+			hosts: []string{"codeload.github.com"},
+			parse: parseCodeload,
+		},
+		gitlab,
+		codeberg,
+		bitbucket,
+		{
+			// release 资源的CDN签名链接 (releases 的重定向目标), 路径中不含用户与仓库
+			name:  "objects",
+			hosts: []string{"objects.githubusercontent.com", "release-assets.githubusercontent.com"},
+			parse: func(segs []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+				return "", "", "objects", nil
+			},
+		},
+	}
+}
+
+// InitMatchers 以内置上游与配置中的 [[upstreams]] 构建匹配注册表
+func InitMatchers(cfg *config.Config) error {
+	kinds := builtinUpstreams(cfg)
+	for i, u := range cfg.Upstreams {
+		kind, err := configUpstream(u)
+		if err != nil {
+			return fmt.Errorf("upstreams[%d]: %w", i, err)
+		}
+		kinds = append(kinds, kind)
+	}
+
+	hosts := make(map[string]*upstreamKind)
+	var wildcard []string
+	for _, kind := range kinds {
+		for _, host := range kind.hosts {
+			host = strings.ToLower(host)
+			if _, exists := hosts[host]; exists {
+				return fmt.Errorf("upstream %s: host %s is already registered", kind.name, host)
+			}
+			hosts[host] = kind
+			if suffix, ok := strings.CutPrefix(host, "*"); ok {
+				wildcard = append(wildcard, suffix)
+			}
+		}
+	}
+	upstreamKinds, upstreamHosts, upstreamWildcard = kinds, hosts, wildcard
+	if len(cfg.Upstreams) > 0 {
+		logInfo("Custom upstreams loaded: %d", len(cfg.Upstreams))
+	}
+	return nil
+}
+
+// configUpstream 将配置中的上游转换为注册表项, 指定 forge 时使用对应平台的路径解析, 否则按 path 模板解析
+func configUpstream(u config.UpstreamConfig) (*upstreamKind, error) {
+	if u.Name == "" || len(u.Hosts) == 0 {
+		return nil, fmt.Errorf("name and hosts are required")
+	}
+	for _, host := range u.Hosts {
+		if strings.Contains(host, "/") || (strings.Contains(host, "*") && !strings.HasPrefix(host, "*.")) {
+			return nil, fmt.Errorf("invalid host %q", host)
+		}
+	}
+	if u.Forge != "" {
+		kind, ok := forgeUpstream(u.Name, strings.ToLower(u.Forge), u.Hosts, u.Shell)
+		if !ok {
+			return nil, fmt.Errorf("unknown forge %q, must be gitlab, gitea, forgejo or bitbucket", u.Forge)
+		}
+		return kind, nil
+	}
+	if u.Matcher == "" {
+		return nil, fmt.Errorf("matcher is required when forge is not set")
+	}
+	client := strings.ToLower(u.Client)
+	switch client {
+	case "":
+		client = upstreamClientProxy
+	case upstreamClientProxy, upstreamClientGit:
+	default:
+		return nil, fmt.Errorf("unknown client %q, must be proxy or git", u.Client)
+	}
+	parse, err := templateParser(u.Name, u.Path, u.Matcher)
+	if err != nil {
+		return nil, err
+	}
+	kind := &upstreamKind{
+		name:    u.Name,
+		hosts:   u.Hosts,
+		parse:   parse,
+		clients: map[string]string{u.Matcher: client},
+		rewrite: u.Shell,
+	}
+	if u.Shell {
+		kind.edit = map[string]struct{}{u.Matcher: {}}
+	}
+	return kind, nil
+}
+
+// templateParser 按 "/:user/:repo/*" 形式的模板解析路径
+// ":user" ":repo" 提取对应路径段, 其余段需完全一致, 末尾的 "*" 匹配任意剩余路径
+func templateParser(name string, template string, matcher string) (pathParser, error) {
+	if template == "" {
+		template = "/:user/:repo/*"
+	}
+	pattern := strings.Split(strings.Trim(template, "/"), "/")
+	wildcard := pattern[len(pattern)-1] == "*"
+	if wildcard {
+		pattern = pattern[:len(pattern)-1]
+	}
+	for i, seg := range pattern {
+		if seg == "" || strings.Contains(seg, "*") || (strings.HasPrefix(seg, ":") && seg != ":user" && seg != ":repo") {
+			return nil, fmt.Errorf("invalid path segment %d in %q", i, template)
+		}
+	}
+
+	return func(segs []string, cfg *config.Config) (string, string, string, *GHProxyErrors) {
+		if len(segs) < len(pattern) || (!wildcard && len(segs) != len(pattern)) {
+			return "", "", "", NewErrorWithStatusLookup(400, fmt.Sprintf("URL didn't match the path %q of upstream %s", template, name))
+		}
+		var user, repo string
+		for i, seg := range pattern {
+			switch seg {
+			case ":user":
+				user = segs[i]
+			case ":repo":
+				repo = segs[i]
+			default:
+				if segs[i] != seg {
+					return "", "", "", NewErrorWithStatusLookup(400, fmt.Sprintf("URL didn't match the path %q of upstream %s", template, name))
+				}
+			}
+		}
+		return user, repo, matcher, nil
+	}, nil
+}
+
+// lookupUpstream 返回 https 链接对应的上游及主机名之后的路径段
+func lookupUpstream(rawURL string) (*upstreamKind, []string) {
+	rest, ok := strings.CutPrefix(rawURL, "https://")
+	if !ok {
+		return nil, nil
+	}
+	rest, _, _ = strings.Cut(rest, "?")
+	rest, _, _ = strings.Cut(rest, "#")
+	host, path, _ := strings.Cut(rest, "/")
+	host = strings.ToLower(host)
+
+	kind, ok := upstreamHosts[host]
+	if !ok {
+		for _, suffix := range upstreamWildcard {
+			if strings.HasSuffix(host, suffix) {
+				kind = upstreamHosts["*"+suffix]
+				break
+			}
+		}
+	}
+	if kind == nil {
+		return nil, nil
+	}
+	return kind, strings.Split(path, "/")
+}
+
+// shellEditable 判断响应是否需要进行 shell 链接改写
+func shellEditable(u string, matcher string) bool {
+	kind, _ := lookupUpstream(u)
+	return kind != nil && kind.editable(matcher)
+}
+
+// isSignedAssetURL 判断是否为 release 资源的CDN签名下载链接
+func isSignedAssetURL(u string) bool {
+	kind, _ := lookupUpstream(u)
+	return kind != nil && kind.name == "objects"
+}
+
+// isAPIPath 判断是否为 api.github.com 的请求, rawPath 可不带 https:// 前缀(快速路由)
+func isAPIPath(rawPath string) bool {
+	kind, _ := lookupUpstream("https://" + strings.TrimPrefix(rawPath, "https://"))
+	return kind != nil && kind.name == "api"
+}
+
+// dispatch 按上游声明的处理方式转发请求
+func dispatch(ctx context.Context, c *app.RequestContext, rawPath string, cfg *config.Config, matcher string) {
+	kind, _ := lookupUpstream(rawPath)
+	if kind == nil {
+		ErrorPage(c, NewErrorWithStatusLookup(500, "Matched But Not Matched"))
+		logError("Matched But Not Matched Path: %s rawPath: %s matcher: %s", c.Path(), rawPath, matcher)
+		return
+	}
+	switch kind.client(matcher) {
+	case upstreamClientGit:
+		GitReq(ctx, c, rawPath, cfg, "git")
+	default:
+		ChunkedProxyRequest(ctx, c, rawPath, cfg, matcher)
+	}
+}
+
+// RegisterRoutes 注册各上游声明的快速路由
+func RegisterRoutes(r *server.Hertz, cfg *config.Config, limiter *rate.RateLimiter, iplimiter *rate.IPRateLimiter) {
+	for _, kind := range upstreamKinds {
+		methods := kind.methods
+		if len(methods) == 0 {
+			methods = []string{http.MethodGet}
+		}
+		for _, route := range kind.routes {
+			matcher := route.matcher
+			for _, host := range kind.hosts {
+				for _, method := range methods {
+					r.Handle(method, "/"+host+route.path, func(ctx context.Context, c *app.RequestContext) {
+						c.Set("matcher", matcher)
+						RoutingHandler(cfg, limiter, iplimiter)(ctx, c)
+					})
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"strings"
+	"testing"
+)
+
+func TestTemplateParser(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		path     string
+		user     string
+		repo     string
+		status   int // 解析失败时的状态码, 0 表示成功
+	}{
+		{"default template", "", "octo/hello/any/file", "octo", "hello", 0},
+		{"default needs user and repo", "", "octo", "", "", 400},
+		{"literal prefix", "/dl/:user/:repo/*", "dl/octo/hello/v1/a.tar.gz", "octo", "hello", 0},
+		{"literal mismatch", "/dl/:user/:repo/*", "get/octo/hello/v1", "", "", 400},
+		{"wildcard may be empty", "/dl/:user/:repo/*", "dl/octo/hello", "octo", "hello", 0},
+		{"exact length", "/:user/:repo/archive", "octo/hello/archive", "octo", "hello", 0},
+		{"exact length rejects extra", "/:user/:repo/archive", "octo/hello/archive/x", "", "", 400},
+		{"repo before user", "/:repo/by/:user/*", "hello/by/octo/f", "octo", "hello", 0},
+		{"no user or repo", "/static/*", "static/a/b", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parse, err := templateParser("custom", tt.template, "raw")
+			if err != nil {
+				t.Fatal(err)
+			}
+			user, repo, matcher, perr := parse(strings.Split(tt.path, "/"), &config.Config{})
+			if tt.status != 0 {
+				if perr == nil || perr.StatusCode != tt.status {
+					t.Fatalf("parse(%q) err = %v, want status %d", tt.path, perr, tt.status)
+				}
+				return
+			}
+			if perr != nil {
+				t.Fatalf("parse(%q) err = %v", tt.path, perr.ErrorMessage)
+			}
+			if user != tt.user || repo != tt.repo || matcher != "raw" {
+				t.Fatalf("parse(%q) = %s, %s, %s; want %s, %s, raw", tt.path, user, repo, matcher, tt.user, tt.repo)
+			}
+		})
+	}
+}
+
+func TestTemplateParserInvalid(t *testing.T) {
+	for _, template := range []string{
+		"/:user//:repo",
+		"/:owner/:repo/*",
+		"/:user/*/:repo",
+		"/:user/:repo/file*",
+	} {
+		if _, err := templateParser("custom", template, "raw"); err == nil {
+			t.Errorf("templateParser(%q) accepted an invalid template", template)
+		}
+	}
+}
@@ -72,4 +72,5 @@ func setRequestHeaders(c *app.RequestContext, req *http.Request, cfg *config.Con
 			}
 		})
 	}
+	applyRequestHeaderRules(c, req.Header, req.URL.String(), matcher)
 }
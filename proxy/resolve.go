@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"ghproxy/resolver"
+	"net/http"
+	"time"
+)
+
+var upstreamResolver *resolver.Resolver
+
+// InitResolver 初始化自定义DNS解析器, 需在构建传输层之前调用
+func InitResolver(cfg *config.Config) {
+	if !cfg.Httpc.DNS.Enabled {
+		return
+	}
+	upstreamResolver = resolver.New(resolver.Options{
+		Hosts:    cfg.Httpc.DNS.Hosts,
+		Servers:  cfg.Httpc.DNS.Servers,
+		Prefer:   cfg.Httpc.DNS.PreferIP,
+		CacheTTL: time.Duration(cfg.Httpc.DNS.CacheTTL) * time.Second,
+		Timeout:  time.Duration(cfg.Httpc.DNS.Timeout) * time.Second,
+	})
+	logInfo("Custom DNS resolver enabled, servers: %v, hosts: %d, prefer: %s", upstreamResolver.Servers(), len(cfg.Httpc.DNS.Hosts), cfg.Httpc.DNS.PreferIP)
+}
+
+// ResolverEntries 返回DNS解析器的服务器列表与缓存项
+func ResolverEntries() (bool, []string, []resolver.Entry) {
+	if upstreamResolver == nil {
+		return false, []string{}, []resolver.Entry{}
+	}
+	return true, upstreamResolver.Servers(), upstreamResolver.Entries()
+}
+
+// applyResolver 将自定义解析接入传输层拨号, 需在 initTransport 之后调用
+// 使用SOCKS5出站代理时, 目标主机在本地解析后以IP形式交给代理
+func applyResolver(transport *http.Transport) {
+	if upstreamResolver == nil {
+		return
+	}
+	transport.DialContext = upstreamResolver.WrapDialer(transportDialContext(transport))
+}
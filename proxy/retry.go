@@ -0,0 +1,246 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ghproxy/breaker"
+	"ghproxy/config"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+)
+
+// retryStatuses 视为暂时性故障, 可在首字节前重试的上游状态码
+var retryStatuses = map[int]struct{}{
+	http.StatusBadGateway:         {},
+	http.StatusServiceUnavailable: {},
+	http.StatusGatewayTimeout:     {},
+}
+
+// upstreamDo 发送上游请求, 按配置对幂等请求进行退避重试, 并为支持 Range 的响应包装断点续传
+func upstreamDo(cl *httpc.Client, req *http.Request, cfg *config.Config) (*http.Response, error) {
+	retry := cfg.Httpc.Retry
+	if !retry.Enabled || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return breakerDo(cl, req)
+	}
+
+	var (
+		resp *http.Response
+		err  error
+	)
+	for attempt := 0; ; attempt++ {
+		resp, err = breakerDo(cl, req)
+		if !shouldRetryUpstream(resp, err) || attempt >= retry.MaxAttempts {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		delay := retryBackoff(retry, attempt)
+		logDebug("Retry upstream %s %s after %v, attempt %d, err: %v", req.Method, req.URL.String(), delay, attempt+1, err)
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+	if err != nil {
+		return resp, err
+	}
+
+	if retry.Resume && req.Method == http.MethodGet {
+		resp.Body = newResumableBody(cl, req, resp, cfg)
+	}
+	return resp, nil
+}
+
+// shouldRetryUpstream 判断上游结果是否可重试
+func shouldRetryUpstream(resp *http.Response, err error) bool {
+	if err != nil {
+		// 客户端已断开或熔断打开时不再重试
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, breaker.ErrOpen)
+	}
+	_, ok := retryStatuses[resp.StatusCode]
+	return ok
+}
+
+// defaultRetryMaxDelay maxDelay 未设置时的退避上限
+const defaultRetryMaxDelay = 30 * time.Second
+
+// retryBackoff 计算带抖动的指数退避时长 (full jitter)
+func retryBackoff(retry config.RetryConfig, attempt int) time.Duration {
+	base := time.Duration(retry.BaseDelay) * time.Millisecond
+	maxDelay := time.Duration(retry.MaxDelay) * time.Millisecond
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultRetryMaxDelay
+	}
+	// 逐次翻倍直至超过上限, 避免大重试次数下移位溢出
+	delay := base
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay <<= 1
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return time.Duration(rand.Int64N(int64(delay)) + 1)
+}
+
+// resumableBody 上游连接中断时以 Range 请求续传并拼接响应体, 客户端无感知
+type resumableBody struct {
+	mu     sync.Mutex
+	body   io.ReadCloser
+	closed bool
+
+	cl        *httpc.Client
+	req       *http.Request
+	cfg       *config.Config
+	validator string // ETag 或 Last-Modified, 用于 If-Range 保证续传的是同一份内容
+	total     int64
+	off       int64
+	resumes   int
+	broken    error
+}
+
+// newResumableBody 仅对完整的 200 响应且上游声明支持 Range 时启用续传
+func newResumableBody(cl *httpc.Client, req *http.Request, resp *http.Response, cfg *config.Config) io.ReadCloser {
+	if resp.StatusCode != http.StatusOK || resp.ContentLength <= 0 {
+		return resp.Body
+	}
+	if !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") || resp.Header.Get("Content-Encoding") != "" {
+		return resp.Body
+	}
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	if validator == "" {
+		return resp.Body
+	}
+
+	// 续传请求发往跟随重定向后的最终URL
+	finalReq := req
+	if resp.Request != nil {
+		finalReq = resp.Request
+	}
+	return &resumableBody{
+		body:      resp.Body,
+		cl:        cl,
+		req:       finalReq,
+		cfg:       cfg,
+		validator: validator,
+		total:     resp.ContentLength,
+	}
+}
+
+func (b *resumableBody) Read(p []byte) (int, error) {
+	for {
+		b.mu.Lock()
+		body, closed := b.body, b.closed
+		b.mu.Unlock()
+		if closed {
+			return 0, http.ErrBodyReadAfterClose
+		}
+
+		if b.broken == nil {
+			n, err := body.Read(p)
+			b.off += int64(n)
+			if err == nil || (errors.Is(err, io.EOF) && b.off >= b.total) {
+				return n, err
+			}
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			b.broken = err
+			if n > 0 {
+				// 先交付已读数据, 下次读取时再续传
+				return n, nil
+			}
+		}
+
+		if err := b.resume(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// resume 以 Range: bytes=off- 重新请求剩余部分
+func (b *resumableBody) resume() error {
+	origErr := b.broken
+	ctx := b.req.Context()
+	if b.resumes >= b.cfg.Httpc.Retry.MaxResumes || ctx.Err() != nil {
+		return origErr
+	}
+	b.resumes++
+
+	select {
+	case <-ctx.Done():
+		return origErr
+	case <-time.After(retryBackoff(b.cfg.Httpc.Retry, b.resumes-1)):
+	}
+
+	req := b.req.Clone(ctx)
+	req.Body = nil
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.off))
+	req.Header.Set("If-Range", b.validator)
+
+	resp, err := breakerDo(b.cl, req)
+	if err != nil {
+		logWarning("Resume %s at %d failed: %v", req.URL.String(), b.off, err)
+		return origErr
+	}
+	if resp.StatusCode != http.StatusPartialContent || !contentRangeStartsAt(resp.Header.Get("Content-Range"), b.off) {
+		resp.Body.Close()
+		logWarning("Resume %s at %d rejected by upstream: %d %s", req.URL.String(), b.off, resp.StatusCode, resp.Header.Get("Content-Range"))
+		return origErr
+	}
+	logInfo("Resumed %s at %d/%d, attempt %d, cause: %v", req.URL.String(), b.off, b.total, b.resumes, origErr)
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		resp.Body.Close()
+		return http.ErrBodyReadAfterClose
+	}
+	old := b.body
+	b.body = resp.Body
+	b.broken = nil
+	b.mu.Unlock()
+	old.Close()
+	return nil
+}
+
+func (b *resumableBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.body.Close()
+}
+
+// contentRangeStartsAt 检查 Content-Range: bytes start-end/total 的起始位置
+func contentRangeStartsAt(contentRange string, off int64) bool {
+	spec, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return false
+	}
+	startStr, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startStr), 10, 64)
+	return err == nil && start == off
+}
@@ -0,0 +1,35 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    int
+		max     int
+		attempt int
+		ceiling time.Duration
+	}{
+		{"first attempt", 100, 3000, 0, 100 * time.Millisecond},
+		{"doubles", 100, 3000, 3, 800 * time.Millisecond},
+		{"clamped", 100, 3000, 10, 3 * time.Second},
+		{"large attempt", 100, 3000, 200, 3 * time.Second},
+		{"no max delay", 100, 0, 5, 3200 * time.Millisecond},
+		{"no max delay large attempt", 100, 0, 200, defaultRetryMaxDelay},
+		{"negative max delay", 100, -1, 64, defaultRetryMaxDelay},
+		{"default base", 0, 0, 1, 200 * time.Millisecond},
+		{"base above max", 5000, 3000, 0, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		retry := config.RetryConfig{BaseDelay: tt.base, MaxDelay: tt.max}
+		for i := 0; i < 100; i++ {
+			if got := retryBackoff(retry, tt.attempt); got <= 0 || got > tt.ceiling {
+				t.Fatalf("%s: retryBackoff(attempt %d) = %v, want (0, %v]", tt.name, tt.attempt, got, tt.ceiling)
+			}
+		}
+	}
+}
@@ -14,6 +14,11 @@ func RoutingHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 
 		var shoudBreak bool
 
+		shoudBreak = banCheck(cfg, c)
+		if shoudBreak {
+			return
+		}
+
 		shoudBreak = rateCheck(cfg, c, limiter, iplimiter)
 		if shoudBreak {
 			return
@@ -48,6 +53,11 @@ func RoutingHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 			return
 		}
 
+		shoudBreak = apiMethodCheck(c, cfg, matcher, rawPath)
+		if shoudBreak {
+			return
+		}
+
 		// 处理blob/raw路径
 		if matcher == "blob" {
 			rawPath = strings.Replace(rawPath, "/blob/", "/raw/", 1)
@@ -58,15 +68,6 @@ func RoutingHandler(cfg *config.Config, limiter *rate.RateLimiter, iplimiter *ra
 
 		logDebug("Matched: %v", matcher)
 
-		switch matcher {
-		case "releases", "blob", "raw", "gist", "api":
-			ChunkedProxyRequest(ctx, c, rawPath, cfg, matcher)
-		case "clone":
-			GitReq(ctx, c, rawPath, cfg, "git")
-		default:
-			ErrorPage(c, NewErrorWithStatusLookup(500, "Matched But Not Matched"))
-			logError("Matched But Not Matched Path: %s rawPath: %s matcher: %s", c.Path(), rawPath, matcher)
-			return
-		}
+		dispatch(ctx, c, rawPath, cfg, matcher)
 	}
 }
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"ghproxy/config"
+	"ghproxy/segment"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+var segmentSlots chan struct{} // 同时进行的分段下载数, 为 nil 表示未启用
+
+// InitSegmented 初始化 release 资源的分段并发下载
+func InitSegmented(cfg *config.Config) error {
+	seg := cfg.Httpc.Segmented
+	if !seg.Enabled {
+		return nil
+	}
+	if seg.Threshold <= 0 || seg.Concurrency <= 0 || seg.SegmentSize <= 0 || seg.MaxActive <= 0 {
+		return fmt.Errorf("httpc.segmented: threshold, concurrency, segmentSize and maxActive must be positive")
+	}
+	segmentSlots = make(chan struct{}, seg.MaxActive)
+	logInfo("Segmented download enabled, threshold: %dMB, concurrency: %d, segmentSize: %dMB, maxActive: %d", seg.Threshold, seg.Concurrency, seg.SegmentSize, seg.MaxActive)
+	return nil
+}
+
+// segmentable 判断上游响应是否可以改为分段下载
+// 需为完整的 200 响应, 声明长度且超过阈值, 支持 Range 且带有可用于 If-Range 的校验值
+func segmentable(c *app.RequestContext, cfg *config.Config, resp *http.Response, matcher string) (string, bool) {
+	if segmentSlots == nil || !isDownloadMatcher(matcher) || string(c.Request.Method()) != http.MethodGet {
+		return "", false
+	}
+	if len(c.Request.Header.Peek("Range")) != 0 || resp.StatusCode != http.StatusOK || resp.Request == nil {
+		return "", false
+	}
+	if resp.ContentLength <= int64(cfg.Httpc.Segmented.Threshold)*1024*1024 {
+		return "", false
+	}
+	if !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") || resp.Header.Get("Content-Encoding") != "" {
+		return "", false
+	}
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	return validator, validator != ""
+}
+
+// newSegmentedBody 将响应体替换为分段并发下载的拼接结果, 无可用名额时返回原响应体
+// 分段请求发往跟随重定向后的最终URL
+func newSegmentedBody(ctx context.Context, cl *httpc.Client, cfg *config.Config, resp *http.Response, validator string) io.ReadCloser {
+	select {
+	case segmentSlots <- struct{}{}:
+	default:
+		logDebug("Segmented download slots exhausted, streaming %s directly", resp.Request.URL.String())
+		return resp.Body
+	}
+
+	seg := cfg.Httpc.Segmented
+	retries := 0
+	if cfg.Httpc.Retry.Enabled {
+		retries = cfg.Httpc.Retry.MaxAttempts
+	}
+	finalReq := resp.Request
+	fetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+		req := finalReq.Clone(ctx)
+		req.Body = nil
+		req.Header.Del("If-None-Match")
+		req.Header.Del("If-Modified-Since")
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+		req.Header.Set("If-Range", validator)
+
+		resp, err := breakerDo(cl, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusPartialContent || !contentRangeStartsAt(resp.Header.Get("Content-Range"), start) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected upstream response: %d %s", resp.StatusCode, resp.Header.Get("Content-Range"))
+		}
+		return resp.Body, nil
+	}
+
+	logDebug("Segmented download %s, size: %d, concurrency: %d", finalReq.URL.String(), resp.ContentLength, seg.Concurrency)
+	r := segment.NewReader(ctx, resp.Body, segment.Options{
+		Size:        resp.ContentLength,
+		SegmentSize: int64(seg.SegmentSize) * 1024 * 1024,
+		Concurrency: seg.Concurrency,
+		Retries:     retries,
+	}, fetch)
+	return &segmentedBody{Reader: r, url: finalReq.URL.String(), size: resp.ContentLength}
+}
+
+// segmentedBody 记录分段下载失败, 读完、出错或关闭时归还名额
+// hertz 按 Content-Length 发送响应体时不会读到 EOF, 外层包装也未必向下传递 Close, 因此读满 size 即释放
+type segmentedBody struct {
+	*segment.Reader
+	url       string
+	size      int64
+	read      int64
+	closeOnce sync.Once
+}
+
+func (b *segmentedBody) Read(p []byte) (int, error) {
+	if b.read >= b.size {
+		b.release()
+		return 0, io.EOF
+	}
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF {
+		logWarning("Segmented download %s aborted: %v", b.url, err)
+	}
+	if err != nil || b.read >= b.size {
+		b.release()
+	}
+	return n, err
+}
+
+func (b *segmentedBody) Close() error {
+	b.release()
+	return nil
+}
+
+// release 取消分段下载并归还名额, 仅执行一次
+func (b *segmentedBody) release() {
+	b.closeOnce.Do(func() {
+		b.Reader.Close()
+		<-segmentSlots
+	})
+}
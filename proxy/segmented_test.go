@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"ghproxy/segment"
+	"io"
+	"testing"
+)
+
+func TestSegmentedBodyRelease(t *testing.T) {
+	data := []byte("0123456789abcdef")
+	size := int64(len(data))
+	tests := []struct {
+		name    string
+		first   []byte
+		read    int // 读取的字节数, -1 表示读到出错或EOF
+		release bool
+	}{
+		{"full length without EOF", data, len(data), true},
+		{"read until EOF", data, -1, true},
+		{"read error", data[:4], -1, true},
+		{"partial read", data, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segmentSlots = make(chan struct{}, 1)
+			defer func() { segmentSlots = nil }()
+			segmentSlots <- struct{}{}
+
+			fetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(data[start : end+1])), nil
+			}
+			r := segment.NewReader(context.Background(), io.NopCloser(bytes.NewReader(tt.first)), segment.Options{
+				Size: size, SegmentSize: 8, Concurrency: 1,
+			}, fetch)
+			b := &segmentedBody{Reader: r, url: tt.name, size: size}
+			if tt.read >= 0 {
+				// 与 hertz 按 Content-Length 发送响应体的方式一致, 不读取 EOF 也不调用 Close
+				if _, err := io.ReadFull(b, make([]byte, tt.read)); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+					t.Fatal(err)
+				}
+			} else {
+				io.Copy(io.Discard, b)
+			}
+			if released := len(segmentSlots) == 0; released != tt.release {
+				t.Fatalf("slot released = %v, want %v", released, tt.release)
+			}
+			b.Close()
+			if len(segmentSlots) != 0 {
+				t.Fatalf("slot not released after Close")
+			}
+		})
+	}
+}
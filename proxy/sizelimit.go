@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"ghproxy/auth"
+	"ghproxy/config"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// 超出大小限制时的处理动作
+const (
+	overLimitRedirect           = "redirect"                // 301 重定向到上游地址
+	overLimitReject             = "reject"                  // 返回 413
+	overLimitAllowAuthenticated = "allow-for-authenticated" // 通过鉴权的请求不受限制, 其余返回 413
+)
+
+var errSizeLimitExceeded = errors.New("response size limit exceeded")
+
+// overLimitAction 返回 matcher 对应的超限动作, 未配置时为 redirect
+func overLimitAction(cfg *config.Config, matcher string) string {
+	action, ok := cfg.OverLimit.Matchers[matcher]
+	if !ok {
+		action = cfg.OverLimit.Action
+	}
+	switch action = strings.ToLower(action); action {
+	case overLimitReject, overLimitAllowAuthenticated:
+		return action
+	default:
+		return overLimitRedirect
+	}
+}
+
+// sizeLimitExempt 判断请求是否不受大小限制
+func sizeLimitExempt(c *app.RequestContext, cfg *config.Config, matcher string) bool {
+	if overLimitAction(cfg, matcher) != overLimitAllowAuthenticated || !cfg.Auth.Enabled {
+		return false
+	}
+	valid, _ := auth.AuthHandler(c, cfg)
+	return valid
+}
+
+// handleOverLimit 响应 Content-Length 已超出限制的上游响应
+func handleOverLimit(c *app.RequestContext, cfg *config.Config, resp *http.Response, matcher string, size int) {
+	finalURL := resp.Request.URL.String()
+	if err := resp.Body.Close(); err != nil {
+		logError("Failed to close response body: %v", err)
+	}
+	if overLimitAction(cfg, matcher) == overLimitRedirect {
+		c.Redirect(http.StatusMovedPermanently, []byte(finalURL))
+		logWarning("%s %s %s %s %s Final-URL: %s Size-Limit-Exceeded: %d", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), finalURL, size)
+		return
+	}
+	ErrorPage(c, NewErrorWithStatusLookup(http.StatusRequestEntityTooLarge, fmt.Sprintf("Response size %d bytes exceeds the limit of %d MB", size, cfg.Server.SizeLimit)))
+	logWarning("%s %s %s %s %s Final-URL: %s Size-Limit-Rejected: %d", c.ClientIP(), c.Method(), c.Path(), c.UserAgent(), c.Request.Header.GetProtocol(), finalURL, size)
+}
+
+// maxEmptyReads 探测超限时允许的连续空读次数, 与 bufio 一致
+const maxEmptyReads = 100
+
+// limitedBody 统计未声明 Content-Length 的响应体(chunked等)大小, 超出限制后中止传输
+// 响应头已发出, 此时无法再重定向或返回错误页, 客户端收到的是被截断的内容
+type limitedBody struct {
+	body  io.ReadCloser
+	limit int64
+	read  int64
+	url   string
+}
+
+func newLimitedBody(body io.ReadCloser, limit int, u string) *limitedBody {
+	return &limitedBody{body: body, limit: int64(limit), url: u}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read >= b.limit {
+		// 已读满限制, 探测一个字节判断是否超限
+		// hertz 的 chunked 写入不接受 0, nil, 连续空读超过上限时以 io.ErrNoProgress 结束
+		var one [1]byte
+		n, err := b.body.Read(one[:])
+		for i := 1; n == 0 && err == nil; i++ {
+			if i >= maxEmptyReads {
+				return 0, io.ErrNoProgress
+			}
+			n, err = b.body.Read(one[:])
+		}
+		if n == 0 {
+			return 0, err
+		}
+		logWarning("Size-Limit-Exceeded while streaming %s, aborted after %d bytes", b.url, b.read)
+		return 0, errSizeLimitExceeded
+	}
+	if remaining := b.limit - b.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+// stallingBody 读到 stallAt 处后先返回 empty 次 (0, nil)
+type stallingBody struct {
+	data    string
+	pos     int
+	stallAt int
+	empty   int
+}
+
+func (s *stallingBody) Read(p []byte) (int, error) {
+	if s.pos >= s.stallAt && s.empty > 0 {
+		s.empty--
+		return 0, nil
+	}
+	if s.pos >= len(s.data) {
+		return 0, io.EOF
+	}
+	n := copy(p, s.data[s.pos:])
+	s.pos += n
+	return n, nil
+}
+
+func (s *stallingBody) Close() error { return nil }
+
+func TestLimitedBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		empty   int // 读满限制后的连续空读次数
+		want    string
+		wantErr error
+	}{
+		{"within limit", "abc", 0, "abc", nil},
+		{"exactly limit", "abcde", 0, "abcde", nil},
+		{"exceeds limit", "abcdef", 0, "abcde", errSizeLimitExceeded},
+		{"empty reads then EOF", "abcde", 10, "abcde", nil},
+		{"empty reads then more data", "abcdef", 10, "abcde", errSizeLimitExceeded},
+		{"no progress", "abcdef", maxEmptyReads, "abcde", io.ErrNoProgress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newLimitedBody(&stallingBody{data: tt.data, stallAt: 5, empty: tt.empty}, 5, "https://example.com/x")
+			got, err := io.ReadAll(b)
+			if string(got) != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("read %q, err %v; want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"ghproxy/config"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// 出站源地址选择策略
+const (
+	sourceStrategyRoundRobin = "round-robin"
+	sourceStrategyHash       = "hash"
+)
+
+var (
+	sourceAddrs []net.IP
+	sourceHash  bool
+	sourceNext  atomic.Uint64
+
+	// sourceClients 按客户端哈希选择源地址时, 每个源地址对应一组独立连接池的客户端
+	sourceClients = make(map[*httpc.Client][]*httpc.Client)
+)
+
+type sourceAddrKey struct{}
+
+// InitSourceAddrs 解析出站源地址配置, 需在构建传输层之前调用
+func InitSourceAddrs(cfg *config.Config) error {
+	sourceAddrs = nil
+	for _, s := range cfg.Outbound.SourceAddrs {
+		ip := net.ParseIP(strings.TrimSpace(s))
+		if ip == nil {
+			return fmt.Errorf("invalid outbound source address: %q", s)
+		}
+		sourceAddrs = append(sourceAddrs, ip)
+	}
+	if len(sourceAddrs) == 0 {
+		return nil
+	}
+
+	switch strings.ToLower(cfg.Outbound.SourceStrategy) {
+	case "", sourceStrategyRoundRobin:
+		sourceHash = false
+	case sourceStrategyHash:
+		sourceHash = true
+	default:
+		return fmt.Errorf("unknown outbound source strategy: %s", cfg.Outbound.SourceStrategy)
+	}
+	logInfo("Outbound source addresses: %v, strategy: %s", sourceAddrs, cfg.Outbound.SourceStrategy)
+	return nil
+}
+
+// sourceDialContext 绑定源地址拨号, 上下文未指定源地址时轮询选择
+// 源地址与目标地址族不一致时由 net.Dialer 过滤目标地址
+func sourceDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	ip, ok := ctx.Value(sourceAddrKey{}).(net.IP)
+	if !ok {
+		ip = sourceAddrs[(sourceNext.Add(1)-1)%uint64(len(sourceAddrs))]
+	}
+	d := &net.Dialer{
+		LocalAddr: &net.TCPAddr{IP: ip},
+		KeepAlive: 30 * time.Second,
+	}
+	return d.DialContext(ctx, network, addr)
+}
+
+// sourceDialer 供 SOCKS5 代理链作为首跳拨号器使用
+type sourceDialer struct{}
+
+func (sourceDialer) Dial(network, addr string) (net.Conn, error) {
+	return sourceDialContext(context.Background(), network, addr)
+}
+
+func (sourceDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return sourceDialContext(ctx, network, addr)
+}
+
+// applySourceAddrs 为未自定义拨号的传输层绑定源地址, 需在 initTransport 之后调用
+func applySourceAddrs(transport *http.Transport) {
+	if len(sourceAddrs) == 0 || transport.DialContext != nil {
+		return
+	}
+	transport.DialContext = sourceDialContext
+}
+
+// buildSourceClients 按客户端哈希选择源地址时, 为每个源地址复制传输层与客户端
+// 连接池按传输层隔离, 保证同一客户端的请求始终从同一源地址发出
+func buildSourceClients(base *httpc.Client, transport *http.Transport, newClient func(*http.Transport) *httpc.Client) {
+	if !sourceHash || len(sourceAddrs) == 0 {
+		return
+	}
+	dial := transportDialContext(transport)
+	clients := make([]*httpc.Client, 0, len(sourceAddrs))
+	for _, ip := range sourceAddrs {
+		t := transport.Clone()
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dial(context.WithValue(ctx, sourceAddrKey{}, ip), network, addr)
+		}
+		clients = append(clients, newClient(t))
+	}
+	sourceClients[base] = clients
+}
+
+// clientFor 返回处理该客户端请求应使用的上游客户端
+func clientFor(base *httpc.Client, c *app.RequestContext) *httpc.Client {
+	clients, ok := sourceClients[base]
+	if !ok {
+		return base
+	}
+	h := fnv.New32a()
+	h.Write([]byte(c.ClientIP()))
+	return clients[h.Sum32()%uint32(len(clients))]
+}
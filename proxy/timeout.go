@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"ghproxy/config"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/httpc"
+)
+
+// errUpstreamIdleTimeout 上游响应体在空闲超时内没有任何数据
+var errUpstreamIdleTimeout = errors.New("upstream read idle timeout")
+
+// bodyIdleTimeout 单次读取上游响应体的最长等待时间, 0 表示不限制
+var bodyIdleTimeout time.Duration
+
+// applyTimeouts 为传输层设置超时, 需在 initTransport 之后调用以包装代理拨号器
+// 为0的字段保持不变, 由httpc使用其默认值
+func applyTimeouts(cfg *config.Config, transport *http.Transport) {
+	timeouts := cfg.Httpc.Timeouts
+	if timeouts.Dial > 0 {
+		transport.DialContext = dialContextWithTimeout(transportDialContext(transport), time.Duration(timeouts.Dial)*time.Second)
+	}
+	if timeouts.TLSHandshake > 0 {
+		transport.TLSHandshakeTimeout = time.Duration(timeouts.TLSHandshake) * time.Second
+	}
+	if timeouts.ResponseHeader > 0 {
+		transport.ResponseHeaderTimeout = time.Duration(timeouts.ResponseHeader) * time.Second
+	}
+	if timeouts.ExpectContinue > 0 {
+		transport.ExpectContinueTimeout = time.Duration(timeouts.ExpectContinue) * time.Second
+	}
+}
+
+// dialContextWithTimeout 为自定义拨号器(如SOCKS5代理链)附加拨号超时
+func dialContextWithTimeout(dial func(ctx context.Context, network, addr string) (net.Conn, error), timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, addr)
+	}
+}
+
+// clientDo 发送请求, 并为响应体附加读取空闲超时
+// 被重定向策略拦截的请求返回触发跳转的重定向响应
+func clientDo(cl *httpc.Client, req *http.Request) (*http.Response, error) {
+	req, stop := withRedirectStop(req)
+	resp, err := cl.Do(req)
+	if redirect := stop.stoppedRedirect(); redirect != nil {
+		return redirect, nil
+	}
+	if err != nil || bodyIdleTimeout <= 0 || resp.Body == nil || resp.Body == http.NoBody {
+		return resp, err
+	}
+	resp.Body = newIdleTimeoutBody(resp.Body, bodyIdleTimeout)
+	return resp, nil
+}
+
+// idleTimeoutBody 单次 Read 超过空闲超时即关闭上游连接, 避免挂起的上游长期占用goroutine
+// 两次 Read 之间(如客户端限速)不计时
+type idleTimeoutBody struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	timedOut atomic.Bool
+}
+
+func newIdleTimeoutBody(body io.ReadCloser, timeout time.Duration) *idleTimeoutBody {
+	b := &idleTimeoutBody{
+		body:    body,
+		timeout: timeout,
+	}
+	b.timer = time.AfterFunc(timeout, func() {
+		b.timedOut.Store(true)
+		b.body.Close()
+	})
+	b.timer.Stop()
+	return b
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	if b.timedOut.Load() {
+		return 0, errUpstreamIdleTimeout
+	}
+	b.timer.Reset(b.timeout)
+	n, err := b.body.Read(p)
+	b.timer.Stop()
+	if err != nil && b.timedOut.Load() {
+		return n, fmt.Errorf("%w after %v", errUpstreamIdleTimeout, b.timeout)
+	}
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.timer.Stop()
+	return b.body.Close()
+}
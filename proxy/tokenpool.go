@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"fmt"
+	"ghproxy/config"
+	"ghproxy/tokenpool"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	tokenPool         *tokenpool.Pool
+	tokenPoolMatchers map[string]struct{}
+)
+
+// tokenPoolHosts 仅向 GitHub 自身的主机注入令牌, 其他上游及自定义上游不注入
+// 跨域重定向(如跳转至 objects.githubusercontent.com)时 Authorization 会被 http 客户端移除
+var tokenPoolHosts = map[string]struct{}{
+	"github.com":                {},
+	"api.github.com":            {},
+	"raw.githubusercontent.com": {},
+}
+
+// accountPaths github.com 上属于账号而非仓库的一级路径, 注入令牌会以令牌所有者的身份访问
+var accountPaths = map[string]struct{}{
+	"account":       {},
+	"login":         {},
+	"logout":        {},
+	"notifications": {},
+	"orgs":          {},
+	"organizations": {},
+	"sessions":      {},
+	"settings":      {},
+	"user":          {},
+	"users":         {},
+}
+
+// InitTokenPool 初始化服务端 GitHub 令牌池
+func InitTokenPool(cfg *config.Config) error {
+	tokenPool = nil
+	if !cfg.TokenPool.Enabled {
+		return nil
+	}
+	if cfg.TokenPool.Quarantine <= 0 {
+		return fmt.Errorf("tokenPool: quarantine must be positive")
+	}
+	pool := tokenpool.New(time.Duration(cfg.TokenPool.Quarantine) * time.Second)
+	for i, token := range cfg.TokenPool.Tokens {
+		// env:NAME 表示从环境变量读取, 避免令牌写入配置文件
+		if name, ok := strings.CutPrefix(token, "env:"); ok {
+			token = os.Getenv(name)
+			if token == "" {
+				return fmt.Errorf("tokenPool: environment variable %s for token %d is empty", name, i)
+			}
+		}
+		if token == "" {
+			return fmt.Errorf("tokenPool: token %d is empty", i)
+		}
+		pool.Add(maskToken(token), token)
+	}
+	if pool.Len() == 0 {
+		return fmt.Errorf("tokenPool: no tokens configured")
+	}
+
+	tokenPoolMatchers = make(map[string]struct{}, len(cfg.TokenPool.Matchers))
+	for _, matcher := range cfg.TokenPool.Matchers {
+		tokenPoolMatchers[matcher] = struct{}{}
+	}
+	tokenPool = pool
+	logInfo("Token pool enabled, tokens: %d, matchers: %v, quarantine: %ds", pool.Len(), cfg.TokenPool.Matchers, cfg.TokenPool.Quarantine)
+	return nil
+}
+
+// TokenPoolStates 返回令牌池中各令牌的用量, 令牌以掩码形式展示
+func TokenPoolStates() (bool, []tokenpool.State) {
+	if tokenPool == nil {
+		return false, nil
+	}
+	return true, tokenPool.States()
+}
+
+// maskToken 仅保留令牌前缀与末尾4位
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "****"
+	}
+	prefix := ""
+	if i := strings.IndexByte(token, '_'); i > 0 && i < 8 {
+		prefix = token[:i+1]
+	}
+	return prefix + "****" + token[len(token)-4:]
+}
+
+// injectPoolToken 为未携带 Authorization 的只读请求注入令牌池中剩余额度最多的令牌
+// 客户端自带的令牌(包括 Auth PassThrough)优先, 仅 GET/HEAD 使用池中令牌, 包括只读 GraphQL 查询在内的 POST 均不注入; 返回 nil 表示未注入
+func injectPoolToken(req *http.Request, matcher string) *tokenpool.Token {
+	if tokenPool == nil || req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil
+	}
+	if _, ok := tokenPoolMatchers[matcher]; !ok {
+		return nil
+	}
+	host := strings.ToLower(req.URL.Hostname())
+	if _, ok := tokenPoolHosts[host]; !ok || req.URL.Scheme != "https" {
+		return nil
+	}
+	if !repoScopedPath(host, req.URL.Path) {
+		return nil
+	}
+	token := tokenPool.Pick()
+	if token == nil {
+		logWarning("Token pool exhausted, forwarding %s anonymously", req.URL.String())
+		return nil
+	}
+	req.Header.Set("Authorization", "token "+token.Value())
+	return token
+}
+
+// repoScopedPath 判断路径是否限定在单个仓库内
+// API 仅允许 /repos/{owner}/{repo}/..., 其余主机要求 /{owner}/{repo}/... 且 owner 不是账号路径,
+// /user*、/notifications、/orgs/... 等以令牌所有者身份访问的路径一律不注入
+func repoScopedPath(host string, path string) bool {
+	segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if host == "api.github.com" {
+		if segments[0] != "repos" {
+			return false
+		}
+		segments = segments[1:]
+	} else if _, ok := accountPaths[strings.ToLower(segments[0])]; ok {
+		return false
+	}
+	if len(segments) < 2 {
+		return false
+	}
+	for _, seg := range segments {
+		// 空段与 . / .. 可能被上游规范化为仓库以外的路径
+		if seg == "" || seg == "." || seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// reportPoolToken 以上游响应更新令牌额度, 401 时隔离令牌
+func reportPoolToken(token *tokenpool.Token, resp *http.Response) {
+	if token == nil {
+		return
+	}
+	tokenPool.Report(token, resp.StatusCode, resp.Header)
+	if resp.StatusCode == http.StatusUnauthorized {
+		logWarning("Token %s rejected by upstream, quarantined", token.Name())
+	}
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"ghproxy/tokenpool"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestInjectPoolToken(t *testing.T) {
+	tokenPool = tokenpool.New(time.Hour)
+	tokenPool.Add("pool", "pool-token")
+	tokenPoolMatchers = map[string]struct{}{"api": {}, "raw": {}, "releases": {}}
+	defer func() { tokenPool, tokenPoolMatchers = nil, nil }()
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		matcher string
+		auth    string
+		want    bool
+	}{
+		{"repo api", "GET", "https://api.github.com/repos/owner/repo/releases/latest", "api", "", true},
+		{"repo root api", "HEAD", "https://api.github.com/repos/owner/repo", "api", "", true},
+		{"user", "GET", "https://api.github.com/user", "api", "", false},
+		{"user emails", "GET", "https://api.github.com/user/emails", "api", "", false},
+		{"user repos", "GET", "https://api.github.com/user/repos?visibility=private", "api", "", false},
+		{"notifications", "GET", "https://api.github.com/notifications", "api", "", false},
+		{"org", "GET", "https://api.github.com/orgs/acme/repos", "api", "", false},
+		{"repos without repo", "GET", "https://api.github.com/repos/owner", "api", "", false},
+		{"dot segments", "GET", "https://api.github.com/repos/owner/repo/../../user", "api", "", false},
+		{"encoded dot segments", "GET", "https://api.github.com/repos/owner/repo/%2E%2E/%2E%2E/user", "api", "", false},
+		{"empty segment", "GET", "https://api.github.com/repos//repo/contents", "api", "", false},
+		{"raw file", "GET", "https://raw.githubusercontent.com/owner/repo/main/README.md", "raw", "", true},
+		{"release asset", "GET", "https://github.com/owner/repo/releases/download/v1/a.zip", "releases", "", true},
+		{"account path", "GET", "https://github.com/settings/tokens", "releases", "", false},
+		{"post", "POST", "https://api.github.com/repos/owner/repo/issues", "api", "", false},
+		{"client token", "GET", "https://api.github.com/repos/owner/repo", "api", "token client", false},
+		{"matcher not enabled", "GET", "https://github.com/owner/repo/blob/main/a", "blob", "", false},
+		{"other host", "GET", "https://gitlab.com/owner/repo/-/raw/main/a", "raw", "", false},
+		{"plain http", "GET", "http://api.github.com/repos/owner/repo", "api", "", false},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		token := injectPoolToken(req, tt.matcher)
+		if got := token != nil; got != tt.want {
+			t.Errorf("%s: injected = %v, want %v", tt.name, got, tt.want)
+		}
+		want := tt.auth
+		if tt.want {
+			want = "token pool-token"
+		}
+		if req.Header.Get("Authorization") != want {
+			t.Errorf("%s: Authorization = %q, want %q", tt.name, req.Header.Get("Authorization"), want)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package proxy
 import (
 	"fmt"
 	"ghproxy/auth"
+	"ghproxy/ban"
 	"ghproxy/config"
 	"ghproxy/rate"
+	"time"
 
 	"github.com/cloudwego/hertz/pkg/app"
 )
@@ -13,11 +15,19 @@ func listCheck(cfg *config.Config, c *app.RequestContext, user string, repo stri
 	if cfg.Auth.ForceAllowApi && cfg.Auth.ForceAllowApiPassList {
 		return false
 	}
+	// CDN 签名链接不含用户与仓库, 无法按名单判断, 仅允许由服务端跟随重定向访问
+	if (cfg.Whitelist.Enabled || cfg.Blacklist.Enabled) && user == "" && repo == "" && isSignedAssetURL(rawPath) {
+		ErrorPage(c, NewErrorWithStatusLookup(403, "Direct release asset links are not allowed while the whitelist or blacklist is enabled, request the release URL instead").WithCode("DIRECT_ASSET_BLOCKED"))
+		logInfo("%s %s %s %s %s List Blocked direct release asset link", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol())
+		return true
+	}
+
 	// 白名单检查
 	if cfg.Whitelist.Enabled {
 		whitelist := auth.CheckWhitelist(user, repo)
 		if !whitelist {
-			ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("Whitelist Blocked repo: %s/%s", user, repo)))
+			ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("Whitelist Blocked repo: %s/%s", user, repo)).WithCode("NOT_WHITELISTED"))
+			ban.Record(c.ClientIP(), ban.SignalList)
 			logInfo("%s %s %s %s %s Whitelist Blocked repo: %s/%s", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol(), user, repo)
 			return true
 		}
@@ -27,7 +37,8 @@ func listCheck(cfg *config.Config, c *app.RequestContext, user string, repo stri
 	if cfg.Blacklist.Enabled {
 		blacklist := auth.CheckBlacklist(user, repo)
 		if blacklist {
-			ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("Blacklist Blocked repo: %s/%s", user, repo)))
+			ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("Blacklist Blocked repo: %s/%s", user, repo)).WithCode("BLACKLISTED"))
+			ban.Record(c.ClientIP(), ban.SignalList)
 			logInfo("%s %s %s %s %s Blacklist Blocked repo: %s/%s", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol(), user, repo)
 			return true
 		}
@@ -40,9 +51,9 @@ func listCheck(cfg *config.Config, c *app.RequestContext, user string, repo stri
 func authCheck(c *app.RequestContext, cfg *config.Config, matcher string, rawPath string) bool {
 	var err error
 
-	if matcher == "api" && !cfg.Auth.ForceAllowApi {
+	if (matcher == "api" || matcher == "archive" && isAPIPath(rawPath)) && !cfg.Auth.ForceAllowApi {
 		if cfg.Auth.Method != "header" || !cfg.Auth.Enabled {
-			ErrorPage(c, NewErrorWithStatusLookup(403, "Github API Req without AuthHeader is Not Allowed"))
+			ErrorPage(c, NewErrorWithStatusLookup(403, "Github API Req without AuthHeader is Not Allowed").WithCode("AUTH_REQUIRED"))
 			logInfo("%s %s %s AuthHeader Unavailable", c.ClientIP(), c.Method(), rawPath)
 			return true
 		}
@@ -54,6 +65,7 @@ func authCheck(c *app.RequestContext, cfg *config.Config, matcher string, rawPat
 		authcheck, err = auth.AuthHandler(c, cfg)
 		if !authcheck {
 			ErrorPage(c, NewErrorWithStatusLookup(401, fmt.Sprintf("Unauthorized: %v", err)))
+			ban.Record(c.ClientIP(), ban.SignalAuth)
 			logInfo("%s %s %s %s %s Auth-Error: %v", c.ClientIP(), c.Method(), rawPath, c.Request.Header.UserAgent(), c.Request.Header.GetProtocol(), err)
 			return true
 		}
@@ -66,13 +78,18 @@ func rateCheck(cfg *config.Config, c *app.RequestContext, limiter *rate.RateLimi
 	// 限制访问频率
 	if cfg.RateLimit.Enabled {
 
-		var allowed bool
+		var (
+			allowed bool
+			limit   int // 取自限流器, 管理接口可在运行时修改
+		)
 
 		switch cfg.RateLimit.RateMethod {
 		case "ip":
 			allowed = iplimiter.Allow(c.ClientIP())
+			limit = iplimiter.Limit()
 		case "total":
 			allowed = limiter.Allow()
+			limit = limiter.Limit()
 		default:
 			logWarning("Invalid RateLimit Method")
 			ErrorPage(c, NewErrorWithStatusLookup(500, "Invalid RateLimit Method"))
@@ -80,7 +97,8 @@ func rateCheck(cfg *config.Config, c *app.RequestContext, limiter *rate.RateLimi
 		}
 
 		if !allowed {
-			ErrorPage(c, NewErrorWithStatusLookup(429, fmt.Sprintf("Too Many Requests; Rate Limit is %d per minute", cfg.RateLimit.RatePerMinute)))
+			ErrorPage(c, NewErrorWithStatusLookup(429, fmt.Sprintf("Too Many Requests; Rate Limit is %d per minute", limit)))
+			ban.Record(c.ClientIP(), ban.SignalRateLimit)
 			logInfo("%s %s %s %s %s 429-TooManyRequests", c.ClientIP(), c.Method(), c.Request.RequestURI(), c.Request.Header.UserAgent(), c.Request.Header.GetProtocol())
 			return true
 		}
@@ -88,3 +106,31 @@ func rateCheck(cfg *config.Config, c *app.RequestContext, limiter *rate.RateLimi
 
 	return false
 }
+
+// 临时封禁检查
+func banCheck(cfg *config.Config, c *app.RequestContext) bool {
+	if !cfg.Ban.Enabled {
+		return false
+	}
+	entry, banned := ban.IsBanned(c.ClientIP())
+	if banned {
+		ErrorPage(c, NewErrorWithStatusLookup(403, fmt.Sprintf("IP Temporarily Banned until %s, reason: %s", entry.ExpiresAt.Format(time.RFC3339), entry.Reason)).WithCode("BANNED"))
+		logDebug("%s %s %s %s %s IP-Banned reason: %s", c.ClientIP(), c.Method(), c.Request.RequestURI(), c.Request.Header.UserAgent(), c.Request.Header.GetProtocol(), entry.Reason)
+		return true
+	}
+	return false
+}
+
+// 设置CORS响应头
+func setCorsHeader(c *app.RequestContext, cfg *config.Config) {
+	switch cfg.Server.Cors {
+	case "*":
+		c.Header("Access-Control-Allow-Origin", "*")
+	case "":
+		c.Header("Access-Control-Allow-Origin", "*")
+	case "nil":
+		c.Header("Access-Control-Allow-Origin", "")
+	default:
+		c.Header("Access-Control-Allow-Origin", cfg.Server.Cors)
+	}
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"ghproxy/config"
+	"net/http"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+func TestListCheckSignedAsset(t *testing.T) {
+	base := config.DefaultConfig()
+	if err := InitMatchers(base); err != nil {
+		t.Fatal(err)
+	}
+	asset := "https://release-assets.githubusercontent.com/github-production-release-asset/1/2?sig=x"
+
+	tests := []struct {
+		name      string
+		whitelist bool
+		blacklist bool
+		passList  bool
+		want      bool
+	}{
+		{"lists disabled", false, false, false, false},
+		{"whitelist enabled", true, false, false, true},
+		{"blacklist enabled", false, true, false, true},
+		{"api pass list", true, false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := *base
+			cfg.Whitelist.Enabled = tt.whitelist
+			cfg.Blacklist.Enabled = tt.blacklist
+			cfg.Auth.ForceAllowApi = tt.passList
+			cfg.Auth.ForceAllowApiPassList = tt.passList
+			c := app.NewContext(0)
+			if got := listCheck(&cfg, c, "", "", asset); got != tt.want {
+				t.Fatalf("listCheck() = %v, want %v", got, tt.want)
+			}
+			if tt.want && c.Response.StatusCode() != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403", c.Response.StatusCode())
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package rate
 
 import (
+	"math"
 	"sync"
 	"time"
 
@@ -20,7 +21,8 @@ var (
 
 // RateLimiter 总体限流器
 type RateLimiter struct {
-	limiter *rate.Limiter
+	limiter  *rate.Limiter
+	duration time.Duration // 限流周期
 }
 
 // New 创建一个总体限流器
@@ -37,7 +39,8 @@ func New(limit int, burst int, duration time.Duration) *RateLimiter {
 	rateLimit := rate.Limit(float64(limit) / duration.Seconds())
 
 	return &RateLimiter{
-		limiter: rate.NewLimiter(rateLimit, burst),
+		limiter:  rate.NewLimiter(rateLimit, burst),
+		duration: duration,
 	}
 }
 
@@ -46,6 +49,29 @@ func (rl *RateLimiter) Allow() bool {
 	return rl.limiter.Allow()
 }
 
+// SetLimit 运行时修改每 duration 时间段内允许的请求数
+func (rl *RateLimiter) SetLimit(limit int) {
+	if limit <= 0 {
+		limit = 1
+		logWarning("rate limit per minute must be positive, setting to 1")
+	}
+	rl.limiter.SetLimit(rate.Limit(float64(limit) / rl.duration.Seconds()))
+}
+
+// Limit 返回当前每 duration 时间段内允许的请求数
+func (rl *RateLimiter) Limit() int {
+	return int(math.Round(float64(rl.limiter.Limit()) * rl.duration.Seconds()))
+}
+
+// SetBurst 运行时修改突发请求数
+func (rl *RateLimiter) SetBurst(burst int) {
+	if burst <= 0 {
+		burst = 1
+		logWarning("rate limit burst must be positive, setting to 1")
+	}
+	rl.limiter.SetBurst(burst)
+}
+
 // IPRateLimiter 基于IP的限流器
 type IPRateLimiter struct {
 	limiters map[string]*RateLimiter // 用户级限流器 map
@@ -105,3 +131,44 @@ func (rl *IPRateLimiter) Allow(ip string) bool {
 
 	return limiter.Allow()
 }
+
+// SetLimit 运行时修改每个IP的请求数限制, 同时作用于已存在的用户级限流器
+func (rl *IPRateLimiter) SetLimit(limit int) {
+	if limit <= 0 {
+		limit = 1
+		logWarning("IP rate limit per minute must be positive, setting to 1")
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.limit = limit
+	for _, limiter := range rl.limiters {
+		limiter.SetLimit(limit)
+	}
+	logInfo("IP Rate Limiter limit updated to: %d", limit)
+}
+
+// Limit 返回当前每个IP每 duration 时间段内允许的请求数
+func (rl *IPRateLimiter) Limit() int {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	return rl.limit
+}
+
+// SetBurst 运行时修改每个IP的突发请求数, 同时作用于已存在的用户级限流器
+func (rl *IPRateLimiter) SetBurst(burst int) {
+	if burst <= 0 {
+		burst = 1
+		logWarning("IP rate limit burst must be positive, setting to 1")
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.burst = burst
+	for _, limiter := range rl.limiters {
+		limiter.SetBurst(burst)
+	}
+	logInfo("IP Rate Limiter burst updated to: %d", burst)
+}
@@ -0,0 +1,277 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WJQSERVER-STUDIO/logger"
+)
+
+// 日志模块
+var (
+	logw       = logger.Logw
+	logDump    = logger.LogDump
+	logDebug   = logger.LogDebug
+	logInfo    = logger.LogInfo
+	logWarning = logger.LogWarning
+	logError   = logger.LogError
+)
+
+// Source 解析结果来源
+type Source string
+
+const (
+	SourceHosts  Source = "hosts"  // 静态hosts映射
+	SourceDNS    Source = "dns"    // 自定义DNS服务器
+	SourceSystem Source = "system" // 系统解析器
+)
+
+// IP 偏好
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+// Entry 解析缓存项
+type Entry struct {
+	Host      string       `json:"host"`
+	Addrs     []netip.Addr `json:"addrs"`
+	Source    Source       `json:"source"`
+	Server    string       `json:"server,omitempty"`
+	ExpiresAt time.Time    `json:"expiresAt"`
+}
+
+// Options 解析器配置
+type Options struct {
+	Hosts    map[string]string // 主机名 -> IP, 多个IP以逗号分隔
+	Servers  []string          // DNS服务器, 支持 udp/tcp/tls(DoT)/https(DoH)
+	Prefer   string            // "ipv4" / "ipv6" / "" 不调整顺序
+	CacheTTL time.Duration     // 缓存时长上限, 实际取记录TTL与该值的较小者
+	Timeout  time.Duration     // 单次查询超时
+}
+
+// Resolver 支持静态hosts、DoH/DoT与TTL缓存的解析器
+type Resolver struct {
+	hosts    map[string][]netip.Addr
+	servers  []upstream
+	prefer   string
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	mu    sync.Mutex
+	cache map[string]*Entry
+}
+
+// New 创建解析器, 无效的hosts项与服务器地址会被忽略并记录日志
+func New(opts Options) *Resolver {
+	r := &Resolver{
+		hosts:    make(map[string][]netip.Addr),
+		prefer:   strings.ToLower(opts.Prefer),
+		cacheTTL: opts.CacheTTL,
+		timeout:  opts.Timeout,
+		cache:    make(map[string]*Entry),
+	}
+	if r.timeout <= 0 {
+		r.timeout = 5 * time.Second
+	}
+	if r.cacheTTL <= 0 {
+		r.cacheTTL = 5 * time.Minute
+	}
+
+	for name, value := range opts.Hosts {
+		var addrs []netip.Addr
+		for _, s := range strings.Split(value, ",") {
+			addr, err := netip.ParseAddr(strings.TrimSpace(s))
+			if err != nil {
+				logWarning("Invalid hosts entry %s = %q: %v", name, s, err)
+				continue
+			}
+			addrs = append(addrs, addr.Unmap())
+		}
+		if len(addrs) > 0 {
+			r.hosts[normalizeHost(name)] = r.sortAddrs(addrs)
+		}
+	}
+
+	for _, s := range opts.Servers {
+		u, err := parseUpstream(s)
+		if err != nil {
+			logWarning("Invalid DNS server %q: %v", s, err)
+			continue
+		}
+		r.servers = append(r.servers, u)
+	}
+	return r
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// LookupNetIP 解析主机名, 依次查询 hosts、缓存、DNS服务器(未配置时使用系统解析器)
+func (r *Resolver) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr}, nil
+	}
+	name := normalizeHost(host)
+	if addrs, ok := r.hosts[name]; ok {
+		return addrs, nil
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	if e, ok := r.cache[name]; ok && now.Before(e.ExpiresAt) {
+		addrs := e.Addrs
+		r.mu.Unlock()
+		return addrs, nil
+	}
+	r.mu.Unlock()
+
+	var (
+		addrs  []netip.Addr
+		ttl    time.Duration
+		source = SourceDNS
+		server string
+		err    error
+	)
+	if len(r.servers) == 0 {
+		source = SourceSystem
+		addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", name)
+		ttl = r.cacheTTL
+	} else {
+		addrs, ttl, server, err = r.queryServers(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+	addrs = r.sortAddrs(addrs)
+
+	if ttl > r.cacheTTL {
+		ttl = r.cacheTTL
+	}
+	if ttl > 0 {
+		r.mu.Lock()
+		r.cache[name] = &Entry{
+			Host:      name,
+			Addrs:     addrs,
+			Source:    source,
+			Server:    server,
+			ExpiresAt: now.Add(ttl),
+		}
+		r.mu.Unlock()
+	}
+	logDebug("Resolved %s via %s %s: %v (ttl %v)", name, source, server, addrs, ttl)
+	return addrs, nil
+}
+
+// queryServers 依次尝试各DNS服务器, 返回第一个成功的结果
+func (r *Resolver) queryServers(ctx context.Context, name string) ([]netip.Addr, time.Duration, string, error) {
+	var errs []error
+	for _, u := range r.servers {
+		qctx, cancel := context.WithTimeout(ctx, r.timeout)
+		addrs, ttl, err := lookupBoth(qctx, u, name)
+		cancel()
+		if err == nil {
+			return addrs, ttl, u.String(), nil
+		}
+		if ctx.Err() != nil {
+			return nil, 0, "", ctx.Err()
+		}
+		logDebug("DNS server %s failed for %s: %v", u, name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", u, err))
+	}
+	return nil, 0, "", fmt.Errorf("lookup %s: %w", name, errors.Join(errs...))
+}
+
+// sortAddrs 按IP偏好排序, 保持同族地址的原有顺序
+func (r *Resolver) sortAddrs(addrs []netip.Addr) []netip.Addr {
+	if r.prefer != PreferIPv4 && r.prefer != PreferIPv6 {
+		return addrs
+	}
+	sort.SliceStable(addrs, func(i, j int) bool {
+		if r.prefer == PreferIPv4 {
+			return addrs[i].Is4() && !addrs[j].Is4()
+		}
+		return addrs[i].Is6() && !addrs[j].Is6()
+	})
+	return addrs
+}
+
+// Entries 返回静态hosts与未过期的缓存项, 用于调试
+func (r *Resolver) Entries() []Entry {
+	now := time.Now()
+	entries := make([]Entry, 0, len(r.hosts))
+	for name, addrs := range r.hosts {
+		entries = append(entries, Entry{Host: name, Addrs: addrs, Source: SourceHosts})
+	}
+
+	r.mu.Lock()
+	for name, e := range r.cache {
+		if now.After(e.ExpiresAt) {
+			delete(r.cache, name)
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Host < entries[j].Host
+	})
+	return entries
+}
+
+// Servers 返回已配置的DNS服务器
+func (r *Resolver) Servers() []string {
+	servers := make([]string, 0, len(r.servers))
+	for _, u := range r.servers {
+		servers = append(servers, u.String())
+	}
+	return servers
+}
+
+// DialFunc 与 http.Transport.DialContext 相同签名的拨号函数
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WrapDialer 在拨号前解析目标主机名, 依次尝试各地址直至成功
+// 下层拨号器(如SOCKS5)接收到的是IP地址, 因此远程解析将被本地解析取代
+func (r *Resolver) WrapDialer(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+		addrs, err := r.LookupNetIP(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var errs []error
+		for _, ip := range addrs {
+			if (network == "tcp4" && !ip.Is4()) || (network == "tcp6" && !ip.Is6()) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("no suitable address found for %s", host)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
@@ -0,0 +1,314 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// maxUDPSize UDP响应缓冲区大小
+const maxUDPSize = 4096
+
+// upstream 一个DNS服务器
+type upstream interface {
+	exchange(ctx context.Context, query []byte) ([]byte, error)
+	String() string
+}
+
+// parseUpstream 解析DNS服务器地址
+// 1.1.1.1 / udp://1.1.1.1:53 / tcp://1.1.1.1:53 / tls://1.1.1.1:853 / https://1.1.1.1/dns-query
+func parseUpstream(s string) (upstream, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("empty server")
+	}
+	if !strings.Contains(s, "://") {
+		return &udpUpstream{addr: withDefaultPort(s, "53")}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "udp":
+		return &udpUpstream{addr: withDefaultPort(u.Host, "53")}, nil
+	case "tcp":
+		return &streamUpstream{addr: withDefaultPort(u.Host, "53")}, nil
+	case "tls":
+		return &streamUpstream{
+			addr:      withDefaultPort(u.Host, "853"),
+			tlsConfig: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12},
+		}, nil
+	case "https":
+		return &dohUpstream{
+			url:    u.String(),
+			client: &http.Client{Transport: &http.Transport{ForceAttemptHTTP2: true, TLSHandshakeTimeout: 5 * time.Second}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+}
+
+// withDefaultPort 补全端口, 兼容裸IPv6地址
+func withDefaultPort(host string, port string) string {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return net.JoinHostPort(addr.String(), port)
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+type udpUpstream struct {
+	addr string
+}
+
+func (u *udpUpstream) String() string {
+	return "udp://" + u.addr
+}
+
+func (u *udpUpstream) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", u.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, maxUDPSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		// 忽略ID不匹配的响应, 防止串包
+		if n >= 2 && bytes.Equal(buf[:2], query[:2]) {
+			resp := buf[:n]
+			var p dnsmessage.Parser
+			if h, err := p.Start(resp); err == nil && h.Truncated {
+				// 响应被截断时改用TCP重新查询
+				return (&streamUpstream{addr: u.addr}).exchange(ctx, query)
+			}
+			return resp, nil
+		}
+	}
+}
+
+// streamUpstream TCP 或 DoT 服务器, 报文带2字节长度前缀
+type streamUpstream struct {
+	addr      string
+	tlsConfig *tls.Config
+}
+
+func (u *streamUpstream) String() string {
+	if u.tlsConfig != nil {
+		return "tls://" + u.addr
+	}
+	return "tcp://" + u.addr
+}
+
+func (u *streamUpstream) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	var (
+		conn net.Conn
+		err  error
+	)
+	if u.tlsConfig != nil {
+		d := &tls.Dialer{Config: u.tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", u.addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", u.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	msg := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(msg, uint16(len(query)))
+	copy(msg[2:], query)
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// dohUpstream DNS over HTTPS (RFC 8484)
+type dohUpstream struct {
+	url    string
+	client *http.Client
+}
+
+func (u *dohUpstream) String() string {
+	return u.url
+}
+
+func (u *dohUpstream) exchange(ctx context.Context, query []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, bytes.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 65535))
+}
+
+// lookupBoth 同时查询 A 与 AAAA 记录, 任一成功即返回, TTL 取所有记录的最小值
+func lookupBoth(ctx context.Context, u upstream, name string) ([]netip.Addr, time.Duration, error) {
+	type result struct {
+		addrs []netip.Addr
+		ttl   time.Duration
+		err   error
+	}
+	results := make(chan result, 2)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		go func(qtype dnsmessage.Type) {
+			addrs, ttl, err := lookup(ctx, u, name, qtype)
+			results <- result{addrs, ttl, err}
+		}(qtype)
+	}
+
+	var (
+		addrs []netip.Addr
+		ttl   time.Duration = -1
+		errs  []error
+	)
+	for range 2 {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		addrs = append(addrs, r.addrs...)
+		if len(r.addrs) > 0 && (ttl < 0 || r.ttl < ttl) {
+			ttl = r.ttl
+		}
+	}
+	if len(addrs) == 0 {
+		if len(errs) > 0 {
+			return nil, 0, errors.Join(errs...)
+		}
+		return nil, 0, fmt.Errorf("no such host: %s", name)
+	}
+	return addrs, ttl, nil
+}
+
+// lookup 查询单一类型的记录, CNAME 链由递归服务器展开, 此处只收集地址记录
+func lookup(ctx context.Context, u upstream, name string, qtype dnsmessage.Type) ([]netip.Addr, time.Duration, error) {
+	qname, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return nil, 0, err
+	}
+	id := uint16(rand.Uint32())
+	if _, ok := u.(*dohUpstream); ok {
+		id = 0 // RFC 8484 建议 DoH 使用ID 0 以利于缓存
+	}
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := u.exchange(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var p dnsmessage.Parser
+	h, err := p.Start(resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	if h.ID != id {
+		return nil, 0, errors.New("mismatched response id")
+	}
+	if h.RCode != dnsmessage.RCodeSuccess {
+		return nil, 0, fmt.Errorf("server returned %s", h.RCode)
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		addrs  []netip.Addr
+		minTTL uint32
+	)
+	for {
+		ah, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch ah.Type {
+		case dnsmessage.TypeA:
+			r, err := p.AResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, netip.AddrFrom4(r.A))
+		case dnsmessage.TypeAAAA:
+			r, err := p.AAAAResource()
+			if err != nil {
+				return nil, 0, err
+			}
+			addrs = append(addrs, netip.AddrFrom16(r.AAAA))
+		default:
+			if err := p.SkipAnswer(); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+		if len(addrs) == 1 || ah.TTL < minTTL {
+			minTTL = ah.TTL
+		}
+	}
+	return addrs, time.Duration(minTTL) * time.Second, nil
+}
@@ -0,0 +1,175 @@
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// FetchFunc 请求 [start, end] 闭区间的数据
+type FetchFunc func(ctx context.Context, start, end int64) (io.ReadCloser, error)
+
+// Options 分段下载配置
+type Options struct {
+	Size        int64 // 资源总长度
+	SegmentSize int64 // 单个分段长度
+	Concurrency int   // 同时下载或缓存的分段数上限, 决定内存上限 Concurrency*SegmentSize
+	Retries     int   // 单个分段失败后的重试次数
+}
+
+// segment 一个已调度的分段, 下载完成后关闭 done
+type segment struct {
+	start int64
+	end   int64
+	buf   []byte
+	err   error
+	done  chan struct{}
+}
+
+// Reader 以多个并发 Range 请求下载资源并按顺序拼接
+// 第一个分段直接读取已有的上游响应体, 其余分段下载到内存后依次交付,
+// 分段在被读取完毕后才释放并调度下一个, 因此读取方的速度(如带宽限制)同样约束上游下载
+type Reader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	opts   Options
+	fetch  FetchFunc
+
+	first    io.ReadCloser
+	firstLen int64
+	firstOff int64
+
+	count    int // 分段总数
+	cur      int // 当前读取的分段
+	next     int // 下一个待调度的分段
+	inflight map[int]*segment
+	off      int // 当前分段内的读取位置
+
+	closeOnce sync.Once
+}
+
+// NewReader 创建分段读取器, first 为从 0 开始的上游响应体, 仅读取其第一个分段
+func NewReader(ctx context.Context, first io.ReadCloser, opts Options, fetch FetchFunc) *Reader {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Reader{
+		ctx:      ctx,
+		cancel:   cancel,
+		opts:     opts,
+		fetch:    fetch,
+		first:    first,
+		firstLen: min(opts.SegmentSize, opts.Size),
+		count:    int((opts.Size + opts.SegmentSize - 1) / opts.SegmentSize),
+		next:     1,
+		inflight: make(map[int]*segment),
+	}
+	for i := 0; i < opts.Concurrency; i++ {
+		r.schedule()
+	}
+	return r
+}
+
+// schedule 调度下一个分段的下载
+func (r *Reader) schedule() {
+	if r.next >= r.count {
+		return
+	}
+	start := int64(r.next) * r.opts.SegmentSize
+	end := min(start+r.opts.SegmentSize, r.opts.Size) - 1
+	s := &segment{start: start, end: end, done: make(chan struct{})}
+	r.inflight[r.next] = s
+	r.next++
+	go r.download(s)
+}
+
+func (r *Reader) download(s *segment) {
+	defer close(s.done)
+	for attempt := 0; attempt <= r.opts.Retries; attempt++ {
+		if r.ctx.Err() != nil {
+			s.err = r.ctx.Err()
+			return
+		}
+		body, err := r.fetch(r.ctx, s.start, s.end)
+		if err == nil {
+			buf := make([]byte, s.end-s.start+1)
+			_, err = io.ReadFull(body, buf)
+			body.Close()
+			if err == nil {
+				s.buf = buf
+				s.err = nil
+				return
+			}
+		}
+		s.err = fmt.Errorf("segment %d-%d: %w", s.start, s.end, err)
+	}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for r.cur < r.count {
+		if r.cur == 0 {
+			n, err := r.readFirst(p)
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		s := r.inflight[r.cur]
+		select {
+		case <-s.done:
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+		if s.err != nil {
+			return 0, s.err
+		}
+		if r.off < len(s.buf) {
+			n := copy(p, s.buf[r.off:])
+			r.off += n
+			return n, nil
+		}
+		// 当前分段读取完毕, 释放后调度下一个
+		delete(r.inflight, r.cur)
+		r.cur++
+		r.off = 0
+		r.schedule()
+	}
+	return 0, io.EOF
+}
+
+// readFirst 读取第一个分段, 读满后关闭原响应体
+func (r *Reader) readFirst(p []byte) (int, error) {
+	if r.firstOff >= r.firstLen {
+		r.first.Close()
+		r.cur++
+		return 0, nil
+	}
+	if remaining := r.firstLen - r.firstOff; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.first.Read(p)
+	r.firstOff += int64(n)
+	if errors.Is(err, io.EOF) {
+		if r.firstOff < r.firstLen {
+			return n, io.ErrUnexpectedEOF
+		}
+		err = nil
+	}
+	return n, err
+}
+
+// Close 取消所有进行中的分段下载
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.first.Close()
+	})
+	return nil
+}
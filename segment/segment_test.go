@@ -0,0 +1,110 @@
+package segment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReader(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	size := int64(len(data))
+
+	tests := []struct {
+		name        string
+		first       []byte
+		segmentSize int64
+		concurrency int
+		retries     int
+		fetch       func(attempt int, start, end int64) []byte // attempt 从 1 开始
+		want        []byte
+		wantErr     error
+	}{
+		{
+			name: "in order", first: data, segmentSize: 10, concurrency: 2,
+			fetch: func(_ int, start, end int64) []byte { return data[start : end+1] },
+			want:  data,
+		},
+		{
+			name: "later segments finish first", first: data, segmentSize: 5, concurrency: 4,
+			fetch: func(_ int, start, end int64) []byte {
+				// 越靠前的分段完成得越晚
+				time.Sleep(time.Duration(size-start) * time.Millisecond)
+				return data[start : end+1]
+			},
+			want: data,
+		},
+		{
+			name: "single segment", first: data, segmentSize: 100, concurrency: 2,
+			fetch: func(_ int, start, end int64) []byte { t.Errorf("unexpected fetch %d-%d", start, end); return nil },
+			want:  data,
+		},
+		{
+			name: "short segment", first: data, segmentSize: 10, concurrency: 2,
+			fetch: func(_ int, start, end int64) []byte {
+				if start == 20 {
+					return data[start:end]
+				}
+				return data[start : end+1]
+			},
+			want: data[:20], wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name: "short segment retried", first: data, segmentSize: 10, concurrency: 2, retries: 1,
+			fetch: func(attempt int, start, end int64) []byte {
+				if attempt == 1 {
+					return data[start:end]
+				}
+				return data[start : end+1]
+			},
+			want: data,
+		},
+		{
+			name: "short first segment", first: data[:7], segmentSize: 10, concurrency: 2,
+			fetch: func(_ int, start, end int64) []byte { return data[start : end+1] },
+			want:  data[:7], wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := make(map[int64]int)
+			fetch := func(ctx context.Context, start, end int64) (io.ReadCloser, error) {
+				mu.Lock()
+				attempts[start]++
+				attempt := attempts[start]
+				mu.Unlock()
+				return io.NopCloser(bytes.NewReader(tt.fetch(attempt, start, end))), nil
+			}
+			r := NewReader(context.Background(), io.NopCloser(bytes.NewReader(tt.first)), Options{
+				Size:        size,
+				SegmentSize: tt.segmentSize,
+				Concurrency: tt.concurrency,
+				Retries:     tt.retries,
+			}, fetch)
+			defer r.Close()
+
+			var got []byte
+			buf := make([]byte, 3)
+			var err error
+			for {
+				var n int
+				n, err = r.Read(buf)
+				got = append(got, buf[:n]...)
+				if err != nil {
+					break
+				}
+			}
+			if tt.wantErr == nil && err != io.EOF || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Fatalf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package tokenpool
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Token 池中的一个令牌
+type Token struct {
+	name  string
+	value string
+
+	// 以下字段由 Pool.mu 保护
+	remaining   int // -1 表示未知
+	limit       int
+	reset       time.Time
+	quarantined time.Time // 隔离截止时间
+	uses        int64
+	failures    int64
+}
+
+// Name 返回令牌名称
+func (t *Token) Name() string {
+	return t.name
+}
+
+// Value 返回令牌内容
+func (t *Token) Value() string {
+	return t.value
+}
+
+// State 令牌状态, 不包含令牌内容
+type State struct {
+	Name             string    `json:"name"`
+	Remaining        int       `json:"remaining"`
+	Limit            int       `json:"limit"`
+	Reset            time.Time `json:"reset"`
+	Uses             int64     `json:"uses"`
+	Unauthorized     int64     `json:"unauthorized"`
+	Quarantined      bool      `json:"quarantined"`
+	QuarantinedUntil time.Time `json:"quarantinedUntil"`
+}
+
+// Pool 按剩余额度轮换的令牌池
+type Pool struct {
+	mu         sync.Mutex
+	tokens     []*Token
+	quarantine time.Duration
+	next       int // 额度相同时轮询的起点
+}
+
+// New 创建令牌池, quarantine 为收到 401 后的隔离时长
+func New(quarantine time.Duration) *Pool {
+	return &Pool{quarantine: quarantine}
+}
+
+// Add 添加令牌, 初始额度未知
+func (p *Pool) Add(name string, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokens = append(p.tokens, &Token{name: name, value: value, remaining: -1})
+}
+
+// Len 返回令牌数量
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.tokens)
+}
+
+// Pick 选择剩余额度最多的可用令牌, 未知额度视为最高; 额度耗尽或隔离中的令牌不参与选择
+// 没有可用令牌时返回 nil
+func (p *Pool) Pick() *Token {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var (
+		best      *Token
+		bestScore int
+	)
+	for i := range p.tokens {
+		t := p.tokens[(p.next+i)%len(p.tokens)]
+		if now.Before(t.quarantined) {
+			continue
+		}
+		score := t.remaining
+		if score < 0 || !t.reset.IsZero() && now.After(t.reset) {
+			score = int(^uint(0) >> 1) // 未知或已过重置时间
+		}
+		if score == 0 {
+			continue
+		}
+		if best == nil || score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	if len(p.tokens) > 0 {
+		p.next = (p.next + 1) % len(p.tokens)
+	}
+	return best
+}
+
+// Report 记录令牌的上游响应, 以 X-RateLimit-* 更新额度, 401 时隔离令牌
+func (p *Pool) Report(t *Token, status int, header http.Header) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t.uses++
+	if status == http.StatusUnauthorized {
+		t.failures++
+		t.quarantined = time.Now().Add(p.quarantine)
+		return
+	}
+	if remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining")); err == nil {
+		t.remaining = remaining
+	}
+	if limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit")); err == nil {
+		t.limit = limit
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		t.reset = time.Unix(reset, 0)
+	}
+}
+
+// States 返回各令牌的状态
+func (p *Pool) States() []State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	states := make([]State, 0, len(p.tokens))
+	for _, t := range p.tokens {
+		states = append(states, State{
+			Name:             t.name,
+			Remaining:        t.remaining,
+			Limit:            t.limit,
+			Reset:            t.reset,
+			Uses:             t.uses,
+			Unauthorized:     t.failures,
+			Quarantined:      now.Before(t.quarantined),
+			QuarantinedUntil: t.quarantined,
+		})
+	}
+	return states
+}
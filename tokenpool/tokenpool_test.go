@@ -0,0 +1,105 @@
+package tokenpool
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func rateHeader(remaining int, reset time.Time) http.Header {
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	h.Set("X-RateLimit-Limit", "5000")
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return h
+}
+
+func TestPickReport(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	type report struct {
+		token     string
+		status    int
+		remaining int // -1 表示不带额度头
+		reset     time.Time
+	}
+	tests := []struct {
+		name    string
+		tokens  []string
+		reports []report
+		want    string // 空表示无可用令牌
+	}{
+		{"empty pool", nil, nil, ""},
+		{"unknown quota first", []string{"a", "b"}, []report{{"a", 200, 100, future}}, "b"},
+		{"most remaining", []string{"a", "b", "c"}, []report{{"a", 200, 10, future}, {"b", 200, 300, future}, {"c", 200, 20, future}}, "b"},
+		{"exhausted skipped", []string{"a", "b"}, []report{{"a", 200, 0, future}, {"b", 200, 1, future}}, "b"},
+		{"all exhausted", []string{"a", "b"}, []report{{"a", 403, 0, future}, {"b", 200, 0, future}}, ""},
+		{"exhausted after reset", []string{"a", "b"}, []report{{"a", 200, 0, past}, {"b", 200, 4000, future}}, "a"},
+		{"unauthorized quarantined", []string{"a", "b"}, []report{{"b", 200, 10, future}, {"a", 401, -1, time.Time{}}}, "b"},
+		{"all quarantined", []string{"a"}, []report{{"a", 401, -1, time.Time{}}}, ""},
+		{"report without headers keeps quota", []string{"a", "b"}, []report{{"a", 200, 10, future}, {"b", 200, 20, future}, {"b", 304, -1, time.Time{}}}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(time.Hour)
+			tokens := make(map[string]*Token)
+			for _, name := range tt.tokens {
+				p.Add(name, "value-"+name)
+			}
+			for _, tok := range p.tokens {
+				tokens[tok.name] = tok
+			}
+			for _, r := range tt.reports {
+				h := http.Header{}
+				if r.remaining >= 0 {
+					h = rateHeader(r.remaining, r.reset)
+				}
+				p.Report(tokens[r.token], r.status, h)
+			}
+			got := p.Pick()
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("Pick() = %s, want nil", got.Name())
+				}
+				return
+			}
+			if got == nil || got.Name() != tt.want || got.Value() != "value-"+tt.want {
+				t.Fatalf("Pick() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPickRotates(t *testing.T) {
+	p := New(time.Hour)
+	p.Add("a", "1")
+	p.Add("b", "2")
+	p.Add("c", "3")
+	seen := make(map[string]int)
+	for i := 0; i < 6; i++ {
+		seen[p.Pick().Name()]++
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if seen[name] != 2 {
+			t.Fatalf("Pick() distribution = %v, want each token twice", seen)
+		}
+	}
+}
+
+func TestQuarantineExpires(t *testing.T) {
+	p := New(10 * time.Millisecond)
+	p.Add("a", "1")
+	p.Report(p.Pick(), http.StatusUnauthorized, http.Header{})
+	if got := p.Pick(); got != nil {
+		t.Fatalf("Pick() during quarantine = %s, want nil", got.Name())
+	}
+	time.Sleep(15 * time.Millisecond)
+	if got := p.Pick(); got == nil {
+		t.Fatalf("Pick() after quarantine = nil, want a")
+	}
+	if s := p.States()[0]; s.Uses != 1 || s.Unauthorized != 1 || s.Quarantined {
+		t.Fatalf("States() = %+v, want 1 use, 1 unauthorized, not quarantined", s)
+	}
+}